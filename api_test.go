@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadAPISpec(t *testing.T) *openapi3.T {
+	doc, err := openapi3.NewLoader().LoadFromFile("_ft/api.yml")
+	if err != nil {
+		t.Fatalf("Loading the API spec: %v", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("Invalid API spec: %v", err)
+	}
+	// match the paths of the test requests whatever their host
+	doc.Servers = nil
+	return doc
+}
+
+func TestResponsesMatchAPISpec(t *testing.T) {
+	assert := assert.New(t)
+	specRouter, err := gorillamux.NewRouter(loadAPISpec(t))
+	if err != nil {
+		t.Fatalf("Routing the API spec: %v", err)
+	}
+
+	auth, _ := newAdminAuth([]string{"ops:s3cret"}, "", false)
+	tests := handlerTests()
+	tests = append(tests, handlerTest{name: "Unauthorized - reload", req: newRequest("POST", "/transformers/organisations/__reload")})
+
+	for _, test := range tests {
+		route, pathParams, err := specRouter.FindRoute(test.req)
+		if !assert.NoError(err, fmt.Sprintf("%s: Route missing from the spec", test.name)) {
+			continue
+		}
+		rec := httptest.NewRecorder()
+		var h http.Handler = router(test.dummyService)
+		if test.dummyService == nil {
+			h = auth.protect(h)
+		}
+		h.ServeHTTP(rec, test.req)
+
+		err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: &openapi3filter.RequestValidationInput{Request: test.req, PathParams: pathParams, Route: route},
+			Status:                 rec.Code,
+			Header:                 rec.Header(),
+			Body:                   ioutil.NopCloser(bytes.NewReader(rec.Body.Bytes())),
+			Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+		})
+		assert.NoError(err, fmt.Sprintf("%s: Response does not match the spec", test.name))
+	}
+}
+
+func TestAPISpecDocumentsEveryRoute(t *testing.T) {
+	assert := assert.New(t)
+	doc := loadAPISpec(t)
+	for _, path := range []string{
+		"/transformers/organisations",
+		"/transformers/organisations/{uuid}",
+		"/transformers/organisations/__count",
+		"/transformers/organisations/__ids",
+		"/transformers/organisations/__conflicts",
+		"/transformers/organisations/__validation",
+		"/transformers/organisations/__reload",
+		"/transformers/organisations/__reload/{id}",
+	} {
+		assert.NotNil(doc.Paths.Find(path), fmt.Sprintf("%s: Missing from the spec", path))
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	apiKeyHeader    = "X-Api-Key"
+	keyIDHeader     = "X-Api-Key-Id"
+	timestampHeader = "X-Timestamp"
+	signatureHeader = "X-Signature"
+	maxClockSkew    = 5 * time.Minute
+)
+
+// adminAuth authenticates requests to admin endpoints against named keys, either sent as they are in X-Api-Key
+// or used to sign the request with HMAC-SHA256. Requests are rejected when no key is configured, unless authentication is disabled.
+type adminAuth struct {
+	sync.Mutex
+	keys     map[string]string
+	disabled bool
+	// signatures seen within the clock skew allowed, with when they expire, so signed requests cannot be replayed
+	signatures map[string]time.Time
+	now        func() time.Time
+}
+
+// newAdminAuth reads name:key pairs from keys and, one per line, from keysFile
+func newAdminAuth(keys []string, keysFile string, disabled bool) (*adminAuth, error) {
+	a := &adminAuth{keys: make(map[string]string), disabled: disabled, signatures: make(map[string]time.Time), now: time.Now}
+	for _, k := range keys {
+		if err := a.addKey(k); err != nil {
+			return nil, err
+		}
+	}
+	if keysFile == "" {
+		return a, nil
+	}
+	f, err := os.Open(keysFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := a.addKey(line); err != nil {
+			return nil, err
+		}
+	}
+	return a, scanner.Err()
+}
+
+func (a *adminAuth) addKey(pair string) error {
+	parts := strings.SplitN(pair, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("Invalid admin key, expected name:key")
+	}
+	if _, ok := a.keys[parts[0]]; ok {
+		return fmt.Errorf("Duplicate admin key name [%v]", parts[0])
+	}
+	a.keys[parts[0]] = parts[1]
+	return nil
+}
+
+func (a *adminAuth) enabled() bool {
+	return len(a.keys) > 0
+}
+
+// signRequest gives the X-Signature of a request made at timestamp to the given method and request URI
+func signRequest(key string, method string, requestURI string, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "%s\n%s\n%s", method, requestURI, timestamp)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// authenticate gives the name of the key the request was made with, or the status to reject it with and why
+func (a *adminAuth) authenticate(r *http.Request) (string, int, string) {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		for name, k := range a.keys {
+			if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+				return name, http.StatusOK, ""
+			}
+		}
+		return "", http.StatusForbidden, "unknown API key"
+	}
+	name := r.Header.Get(keyIDHeader)
+	signature := r.Header.Get(signatureHeader)
+	timestamp := r.Header.Get(timestampHeader)
+	if name == "" && signature == "" {
+		return "", http.StatusUnauthorized, "no credentials"
+	}
+	key, ok := a.keys[name]
+	if !ok {
+		return name, http.StatusForbidden, "unknown key ID"
+	}
+	secs, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return name, http.StatusForbidden, "invalid timestamp"
+	}
+	if skew := a.now().Sub(time.Unix(secs, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+		return name, http.StatusForbidden, "stale timestamp"
+	}
+	if !hmac.Equal([]byte(signature), []byte(signRequest(key, r.Method, r.URL.RequestURI(), timestamp))) {
+		return name, http.StatusForbidden, "invalid signature"
+	}
+	if !a.firstUse(signature, time.Unix(secs, 0).Add(maxClockSkew)) {
+		return name, http.StatusForbidden, "replayed signature"
+	}
+	return name, http.StatusOK, ""
+}
+
+// firstUse records a signature until it expires, telling whether it was not seen before
+func (a *adminAuth) firstUse(signature string, expires time.Time) bool {
+	a.Lock()
+	defer a.Unlock()
+	now := a.now()
+	for s, e := range a.signatures {
+		if now.After(e) {
+			delete(a.signatures, s)
+		}
+	}
+	if _, seen := a.signatures[signature]; seen {
+		return false
+	}
+	a.signatures[signature] = expires
+	return true
+}
+
+// protect rejects unauthenticated requests with a 401 and wrongly authenticated ones with a 403, audit logging every attempt.
+// Without keys every request is rejected with a 503, unless authentication is disabled.
+func (a *adminAuth) protect(next http.Handler) http.Handler {
+	if a.disabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.enabled() {
+			log.WithFields(log.Fields{"audit": true, "method": r.Method, "uri": r.URL.RequestURI(), "remoteAddr": r.RemoteAddr}).Warn("Admin request denied, no admin keys are configured")
+			writeJSONError(w, r, http.StatusServiceUnavailable, codeUnavailable, "No admin keys are configured")
+			return
+		}
+		client, status, reason := a.authenticate(r)
+		audit := log.WithFields(log.Fields{
+			"audit":         true,
+			"client":        client,
+			"method":        r.Method,
+			"uri":           r.URL.RequestURI(),
+			"remoteAddr":    r.RemoteAddr,
+			"transactionID": transactionID(w, r),
+		})
+		if status != http.StatusOK {
+			audit.WithField("reason", reason).Warn("Admin request denied")
+			code := codeForbidden
+			if status == http.StatusUnauthorized {
+				w.Header().Set("WWW-Authenticate", apiKeyHeader)
+				code = codeUnauthorized
+			}
+			writeJSONError(w, r, status, code, http.StatusText(status))
+			return
+		}
+		audit.Info("Admin request allowed")
+		next.ServeHTTP(w, r)
+	})
+}
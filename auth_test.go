@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signedRequest(method, uri, keyID, key string, at time.Time) *http.Request {
+	req := newRequest(method, uri)
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	req.Header.Set(keyIDHeader, keyID)
+	req.Header.Set(timestampHeader, timestamp)
+	req.Header.Set(signatureHeader, signRequest(key, method, uri, timestamp))
+	return req
+}
+
+func withAPIKey(req *http.Request, key string) *http.Request {
+	req.Header.Set(apiKeyHeader, key)
+	return req
+}
+
+func TestAdminAuth(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(1700000000, 0)
+	auth, err := newAdminAuth([]string{"ops:s3cret", "ci:other"}, "", false)
+	assert.NoError(err)
+	auth.now = func() time.Time { return now }
+	closed, err := newAdminAuth(nil, "", false)
+	assert.NoError(err)
+	open, err := newAdminAuth(nil, "", true)
+	assert.NoError(err)
+
+	const uri = "/transformers/organisations/__reload?resume=true"
+	tests := []struct {
+		name       string
+		auth       *adminAuth
+		req        *http.Request
+		statusCode int
+	}{
+		{"No keys configured", closed, newRequest("POST", uri), http.StatusServiceUnavailable},
+		{"Authentication disabled", open, newRequest("POST", uri), http.StatusAccepted},
+		{"No credentials", auth, newRequest("POST", uri), http.StatusUnauthorized},
+		{"Valid API key", auth, withAPIKey(newRequest("POST", uri), "other"), http.StatusAccepted},
+		{"Unknown API key", auth, withAPIKey(newRequest("POST", uri), "guess"), http.StatusForbidden},
+		{"Valid signature", auth, signedRequest("POST", uri, "ops", "s3cret", now), http.StatusAccepted},
+		{"Signature with another key", auth, signedRequest("POST", uri, "ops", "other", now), http.StatusForbidden},
+		{"Unknown key ID", auth, signedRequest("POST", uri, "dev", "s3cret", now), http.StatusForbidden},
+		{"Stale timestamp", auth, signedRequest("POST", uri, "ops", "s3cret", now.Add(-10*time.Minute)), http.StatusForbidden},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusAccepted) })
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		test.auth.protect(next).ServeHTTP(rec, test.req)
+		assert.Equal(test.statusCode, rec.Code, fmt.Sprintf("%s: Wrong response code", test.name))
+	}
+
+	tampered := signedRequest("POST", "/transformers/organisations/__reload", "ops", "s3cret", now)
+	tampered.URL.RawQuery = "resume=true"
+	rec := httptest.NewRecorder()
+	auth.protect(next).ServeHTTP(rec, tampered)
+	assert.Equal(http.StatusForbidden, rec.Code, "Signature should cover the query")
+}
+
+func TestAdminAuthRejectsReplayedSignatures(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(1700000000, 0)
+	auth, err := newAdminAuth([]string{"ops:s3cret"}, "", false)
+	assert.NoError(err)
+	auth.now = func() time.Time { return now }
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusAccepted) })
+	const uri = "/transformers/organisations/__reload"
+	signedAt := now
+
+	tests := []struct {
+		name       string
+		advance    time.Duration
+		statusCode int
+	}{
+		{"First use", 0, http.StatusAccepted},
+		{"Replayed", time.Minute, http.StatusForbidden},
+		{"Replayed at the end of the window", maxClockSkew - time.Minute, http.StatusForbidden},
+		{"Expired", time.Second, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		now = now.Add(test.advance)
+		rec := httptest.NewRecorder()
+		auth.protect(next).ServeHTTP(rec, signedRequest("POST", uri, "ops", "s3cret", signedAt))
+		assert.Equal(test.statusCode, rec.Code, fmt.Sprintf("%s: Wrong response code", test.name))
+	}
+
+	rec := httptest.NewRecorder()
+	auth.protect(next).ServeHTTP(rec, signedRequest("POST", uri, "ops", "s3cret", now))
+	assert.Equal(http.StatusAccepted, rec.Code, "A new signature should be accepted")
+	assert.Len(auth.signatures, 1, "Expired signatures should be forgotten")
+}
+
+func TestAdminKeysFile(t *testing.T) {
+	assert := assert.New(t)
+	f, err := ioutil.TempFile("", "admin-keys")
+	assert.NoError(err)
+	defer os.Remove(f.Name())
+	f.WriteString("# operators\nops:s3cret\n\nci:other\n")
+	f.Close()
+
+	auth, err := newAdminAuth([]string{"dev:mine"}, f.Name(), false)
+	assert.NoError(err)
+	assert.Equal(map[string]string{"ops": "s3cret", "ci": "other", "dev": "mine"}, auth.keys)
+
+	_, err = newAdminAuth([]string{"ops:s3cret"}, f.Name(), false)
+	assert.Error(err, "Duplicate key names should be rejected")
+	_, err = newAdminAuth([]string{"s3cret"}, "", false)
+	assert.Error(err, "Keys without a name should be rejected")
+	_, err = newAdminAuth(nil, "does-not-exist", false)
+	assert.Error(err, "Missing keys file should be rejected")
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/jawher/mow.cli"
+	"github.com/pborman/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// cacheCommand inspects the org bucket of an existing cache file, opened read-only
+func cacheCommand(cacheFileName *string) func(cmd *cli.Cmd) {
+	return func(cmd *cli.Cmd) {
+		cmd.Command("stats", "Show the number of cached organisations and the size of the cache file", func(c *cli.Cmd) {
+			c.Action = func() {
+				withCache(*cacheFileName, func(db *bolt.DB) error {
+					return cacheStats(db, os.Stdout)
+				})
+			}
+		})
+		cmd.Command("get", "Print the cached organisation with the given UUID", func(c *cli.Cmd) {
+			orgUUID := c.StringArg("UUID", "", "UUID of the organisation")
+			c.Action = func() {
+				withCache(*cacheFileName, func(db *bolt.DB) error {
+					return cacheGet(db, *orgUUID, os.Stdout)
+				})
+			}
+		})
+		cmd.Command("list", "List the UUID and prefLabel of every cached organisation", func(c *cli.Cmd) {
+			c.Action = func() {
+				withCache(*cacheFileName, func(db *bolt.DB) error {
+					return cacheList(db, os.Stdout)
+				})
+			}
+		})
+		cmd.Command("dump", "Print every cached organisation as NDJSON", func(c *cli.Cmd) {
+			fieldList := c.StringOpt("fields", "", "Comma-separated fields to print, e.g. uuid,prefLabel, all of them by default")
+			c.Action = func() {
+				fields, err := parseFields(*fieldList)
+				if err != nil {
+					log.Fatalf("%v", err)
+				}
+				withCache(*cacheFileName, func(db *bolt.DB) error {
+					return cacheDump(db, fields, os.Stdout)
+				})
+			}
+		})
+		cmd.Command("verify", "Check the cache file and every cached organisation, exiting with 1 on problems", func(c *cli.Cmd) {
+			c.Action = func() {
+				withCache(*cacheFileName, func(db *bolt.DB) error {
+					problems, err := cacheVerify(db, os.Stdout)
+					if err == nil && problems > 0 {
+						return fmt.Errorf("Cache verification found %d problems", problems)
+					}
+					return err
+				})
+			}
+		})
+	}
+}
+
+func openCacheReadOnly(path string) (*bolt.DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{ReadOnly: true, Timeout: 1 * time.Second})
+	if err == bolt.ErrTimeout {
+		return nil, fmt.Errorf("Cache file [%v] is locked, is the service running on it?", path)
+	}
+	return db, err
+}
+
+func withCache(path string, f func(db *bolt.DB) error) {
+	db, err := openCacheReadOnly(path)
+	if err != nil {
+		log.Fatalf("Error opening cache file: %v", err)
+	}
+	err = f(db)
+	db.Close()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+func forEachCachedOrg(db *bolt.DB, f func(k []byte, v []byte) error) error {
+	return db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(cacheBucket))
+		if bucket == nil {
+			return fmt.Errorf("Bucket %v not found!", cacheBucket)
+		}
+		return bucket.ForEach(f)
+	})
+}
+
+func cacheStats(db *bolt.DB, w io.Writer) error {
+	return db.View(func(tx *bolt.Tx) error {
+		fmt.Fprintf(w, "file: %v\n", db.Path())
+		fmt.Fprintf(w, "size: %d bytes\n", tx.Size())
+		for _, name := range []string{cacheBucket, stagingBucket} {
+			bucket := tx.Bucket([]byte(name))
+			if bucket == nil {
+				fmt.Fprintf(w, "%v: not present\n", name)
+				continue
+			}
+			stats := bucket.Stats()
+			fmt.Fprintf(w, "%v: %d organisations, %d bytes in use, depth %d\n", name, stats.KeyN, stats.LeafInuse+stats.BranchInuse, stats.Depth)
+		}
+		return nil
+	})
+}
+
+func cacheGet(db *bolt.DB, orgUUID string, w io.Writer) error {
+	var value []byte
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(cacheBucket))
+		if bucket == nil {
+			return fmt.Errorf("Bucket %v not found!", cacheBucket)
+		}
+		value = append(value, bucket.Get([]byte(orgUUID))...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(value) == 0 {
+		return fmt.Errorf("No cached organisation for [%v]", orgUUID)
+	}
+	var anOrg org
+	if err := json.Unmarshal(value, &anOrg); err != nil {
+		return fmt.Errorf("Could not unmarshal cached organisation [%v]: %v", orgUUID, err)
+	}
+	out, err := json.MarshalIndent(anOrg, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
+
+func cacheList(db *bolt.DB, w io.Writer) error {
+	return forEachCachedOrg(db, func(k []byte, v []byte) error {
+		var anOrg org
+		if err := json.Unmarshal(v, &anOrg); err != nil {
+			return fmt.Errorf("Could not unmarshal cached organisation [%s]: %v", k, err)
+		}
+		_, err := fmt.Fprintf(w, "%s\t%s\n", k, anOrg.PrefLabel)
+		return err
+	})
+}
+
+// cacheDump prints the cached orgs as stored, or only the given fields of each
+func cacheDump(db *bolt.DB, fields []string, w io.Writer) error {
+	return forEachCachedOrg(db, func(k []byte, v []byte) error {
+		if len(fields) > 0 {
+			selected, err := selectEncodedFields(v, fields)
+			if err != nil {
+				return fmt.Errorf("Could not unmarshal cached organisation [%s]: %v", k, err)
+			}
+			if v, err = json.Marshal(selected); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "%s\n", v)
+		return err
+	})
+}
+
+// cacheVerify checks the consistency of the bolt file and that every cached org decodes and matches its key and TME identifier
+func cacheVerify(db *bolt.DB, w io.Writer) (int, error) {
+	problems := 0
+	err := db.View(func(tx *bolt.Tx) error {
+		for err := range tx.Check() {
+			problems++
+			fmt.Fprintf(w, "file: %v\n", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return problems, err
+	}
+	checked := 0
+	err = forEachCachedOrg(db, func(k []byte, v []byte) error {
+		checked++
+		for _, p := range verifyCachedOrg(string(k), v) {
+			problems++
+			fmt.Fprintf(w, "%s: %v\n", k, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return problems, err
+	}
+	fmt.Fprintf(w, "%d organisations checked, %d problems found\n", checked, problems)
+	return problems, nil
+}
+
+func verifyCachedOrg(key string, value []byte) []string {
+	var anOrg org
+	if err := json.Unmarshal(value, &anOrg); err != nil {
+		return []string{fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	var problems []string
+	if anOrg.UUID != key {
+		problems = append(problems, fmt.Sprintf("stored under a different UUID than its own [%v]", anOrg.UUID))
+	}
+	if len(anOrg.AlternativeIdentifiers.TME) != 1 {
+		problems = append(problems, fmt.Sprintf("expected one TME identifier, found %d", len(anOrg.AlternativeIdentifiers.TME)))
+	} else if derived := uuid.NewMD5(uuid.UUID{}, []byte(anOrg.AlternativeIdentifiers.TME[0])).String(); derived != anOrg.UUID {
+		problems = append(problems, fmt.Sprintf("TME identifier derives UUID [%v]", derived))
+	}
+	if len(anOrg.AlternativeIdentifiers.Uuids) != 1 || anOrg.AlternativeIdentifiers.Uuids[0] != anOrg.UUID {
+		problems = append(problems, fmt.Sprintf("alternative UUIDs %v do not match", anOrg.AlternativeIdentifiers.Uuids))
+	}
+	return problems
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/assert"
+)
+
+const europeanUnionUUID = "6a7edb42-c27a-3186-a0b9-7e3cdc91e16b"
+
+// writeTestCache creates a cache file holding the given raw values by UUID and reopens it read-only
+func writeTestCache(assert *assert.Assertions, file string, values map[string][]byte) *bolt.DB {
+	db, err := bolt.Open(file, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	assert.NoError(err)
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket([]byte(cacheBucket))
+		if err != nil {
+			return err
+		}
+		for k, v := range values {
+			if err := bucket.Put([]byte(k), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(err)
+	db.Close()
+	db, err = openCacheReadOnly(file)
+	assert.NoError(err)
+	return db
+}
+
+func europeanUnion() []byte {
+	b, _ := json.Marshal(transformOrg(term{CanonicalName: "European Union", RawID: "Nstein_GL_US_NY_Municipality_942968"}, "ON"))
+	return b
+}
+
+func TestCacheCommands(t *testing.T) {
+	assert := assert.New(t)
+	db := writeTestCache(assert, testCacheFile(t), map[string][]byte{europeanUnionUUID: europeanUnion()})
+	defer db.Close()
+
+	var out bytes.Buffer
+	assert.NoError(cacheStats(db, &out))
+	assert.Contains(out.String(), "org: 1 organisations")
+	assert.Contains(out.String(), "org_staging: not present")
+
+	out.Reset()
+	assert.NoError(cacheGet(db, europeanUnionUUID, &out))
+	assert.Contains(out.String(), `"prefLabel": "European Union"`)
+	assert.Error(cacheGet(db, "unknown", &out), "Unknown UUID should be an error")
+
+	out.Reset()
+	assert.NoError(cacheList(db, &out))
+	assert.Equal(europeanUnionUUID+"\tEuropean Union\n", out.String())
+
+	out.Reset()
+	assert.NoError(cacheDump(db, nil, &out))
+	assert.Equal(string(europeanUnion())+"\n", out.String())
+
+	out.Reset()
+	assert.NoError(cacheDump(db, []string{"uuid", "prefLabel"}, &out))
+	assert.Equal(`{"uuid":"`+europeanUnionUUID+`","prefLabel":"European Union"}`+"\n", out.String())
+
+	out.Reset()
+	problems, err := cacheVerify(db, &out)
+	assert.NoError(err)
+	assert.Equal(0, problems, out.String())
+}
+
+func TestCacheVerifyFindsProblems(t *testing.T) {
+	assert := assert.New(t)
+	db := writeTestCache(assert, testCacheFile(t), map[string][]byte{
+		"00000000-0000-0000-0000-000000000000": europeanUnion(),
+		"11111111-1111-1111-1111-111111111111": []byte("{not json"),
+	})
+	defer db.Close()
+
+	var out bytes.Buffer
+	problems, err := cacheVerify(db, &out)
+	assert.NoError(err)
+	assert.Equal(2, problems, out.String())
+	assert.True(strings.Contains(out.String(), "stored under a different UUID"))
+	assert.True(strings.Contains(out.String(), "invalid JSON"))
+}
+
+func TestOpenMissingCache(t *testing.T) {
+	_, err := openCacheReadOnly("does-not-exist.db")
+	assert.Error(t, err)
+}
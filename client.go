@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/sethgrid/pester"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+var backoffStrategies = map[string]pester.BackoffStrategy{
+	"default":            pester.DefaultBackoff,
+	"linear":             pester.LinearBackoff,
+	"linear-jitter":      pester.LinearJitterBackoff,
+	"exponential":        pester.ExponentialBackoff,
+	"exponential-jitter": pester.ExponentialJitterBackoff,
+}
+
+func backoffStrategyNames() []string {
+	var names []string
+	for name := range backoffStrategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// clientConfig holds the pester settings of the TME client
+type clientConfig struct {
+	retries     int
+	timeout     time.Duration
+	backoff     string
+	concurrency int
+}
+
+func (c clientConfig) String() string {
+	return fmt.Sprintf("retries=%d timeout=%v backoff=%v concurrency=%d", c.retries, c.timeout, c.backoff, c.concurrency)
+}
+
+// newTLSConfig builds the TLS configuration of the TME client. Certificates are verified unless insecure is set.
+// The CA bundle, if any, is trusted on top of the system roots.
+func newTLSConfig(insecure bool, caBundle string, clientCert string, clientKey string) (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: insecure}
+	if caBundle != "" {
+		pem, err := ioutil.ReadFile(caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read CA bundle [%v]: %v", caBundle, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificates found in CA bundle [%v]", caBundle)
+		}
+		cfg.RootCAs = pool
+	}
+	if clientCert != "" || clientKey != "" {
+		if clientCert == "" || clientKey == "" {
+			return nil, errors.New("Both a client certificate and a client key are needed")
+		}
+		cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("Could not load client certificate [%v]: %v", clientCert, err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+func newTransport(tlsConfig *tls.Config, timeout time.Duration) *http.Transport {
+	return &http.Transport{
+		MaxIdleConnsPerHost: 32,
+		TLSClientConfig:     tlsConfig,
+		Dial: (&net.Dialer{
+			Timeout:   timeout,
+			KeepAlive: 30 * time.Second,
+		}).Dial,
+	}
+}
+
+func getResilientClient(tlsConfig *tls.Config, cfg clientConfig) (*pester.Client, error) {
+	backoff, ok := backoffStrategies[cfg.backoff]
+	if !ok {
+		return nil, fmt.Errorf("Unknown backoff strategy [%v], expected one of %v", cfg.backoff, backoffStrategyNames())
+	}
+	// tmereader builds its requests without a context, so these spans start traces of their own rather than joining the load's
+	c := &http.Client{
+		Transport: otelhttp.NewTransport(newTransport(tlsConfig, cfg.timeout)),
+		Timeout:   cfg.timeout,
+	}
+	client := pester.NewExtendedClient(c)
+	client.Backoff = backoff
+	client.MaxRetries = cfg.retries
+	client.Concurrency = cfg.concurrency
+	client.LogHook = retryMetricsHook(cfg.retries)
+
+	return client, nil
+}
+
+// retryMetricsHook counts the failed TME requests pester retries and the ones it finally gives up on
+func retryMetricsHook(maxRetries int) func(e pester.ErrEntry) {
+	return func(e pester.ErrEntry) {
+		if e.Retry < maxRetries {
+			tmeClientRetries.Inc()
+			return
+		}
+		tmeClientFailures.Inc()
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sethgrid/pester"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLSConfig(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	bundle, err := ioutil.TempFile("", "ca-bundle")
+	assert.NoError(err)
+	defer os.Remove(bundle.Name())
+	pem.Encode(bundle, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	bundle.Close()
+
+	tests := []struct {
+		name     string
+		insecure bool
+		caBundle string
+		reached  bool
+	}{
+		{"Strict verification rejects unknown CA", false, "", false},
+		{"Custom CA bundle", false, bundle.Name(), true},
+		{"Insecure", true, "", true},
+	}
+
+	for _, test := range tests {
+		cfg, err := newTLSConfig(test.insecure, test.caBundle, "", "")
+		assert.NoError(err, fmt.Sprintf("%s: Unexpected error", test.name))
+		client := &http.Client{Transport: newTransport(cfg, 5*time.Second)}
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		assert.Equal(test.reached, err == nil, fmt.Sprintf("%s: Unexpected result %v", test.name, err))
+	}
+}
+
+func TestInvalidTLSConfig(t *testing.T) {
+	assert := assert.New(t)
+	empty, err := ioutil.TempFile("", "ca-bundle")
+	assert.NoError(err)
+	defer os.Remove(empty.Name())
+	empty.Close()
+
+	tests := []struct {
+		name       string
+		caBundle   string
+		clientCert string
+		clientKey  string
+	}{
+		{"Missing CA bundle", "does-not-exist.pem", "", ""},
+		{"CA bundle without certificates", empty.Name(), "", ""},
+		{"Client certificate without key", "", "cert.pem", ""},
+		{"Missing client certificate", "", "does-not-exist.pem", "does-not-exist.key"},
+	}
+
+	for _, test := range tests {
+		_, err := newTLSConfig(false, test.caBundle, test.clientCert, test.clientKey)
+		assert.Error(err, fmt.Sprintf("%s: Expected an error", test.name))
+	}
+}
+
+func TestResilientClientConfig(t *testing.T) {
+	assert := assert.New(t)
+	client, err := getResilientClient(&tls.Config{}, clientConfig{retries: 3, timeout: 10 * time.Second, backoff: "linear", concurrency: 2})
+	assert.NoError(err)
+	assert.Equal(3, client.MaxRetries)
+	assert.Equal(2, client.Concurrency)
+
+	_, err = getResilientClient(&tls.Config{}, clientConfig{retries: 3, timeout: 10 * time.Second, backoff: "random", concurrency: 1})
+	assert.Error(err, "Unknown backoff strategy should be rejected")
+}
+
+func TestRetryMetricsHook(t *testing.T) {
+	assert := assert.New(t)
+	hook := retryMetricsHook(3)
+	r, f := metricValue(t, "tme_client_retries_total"), metricValue(t, "tme_client_failures_total")
+
+	hook(pester.ErrEntry{Retry: 1})
+	hook(pester.ErrEntry{Retry: 2})
+	hook(pester.ErrEntry{Retry: 3})
+
+	assert.Equal(r+2, metricValue(t, "tme_client_retries_total"), "Retry count on /metrics incorrect")
+	assert.Equal(f+1, metricValue(t, "tme_client_failures_total"), "Failure count on /metrics incorrect")
+}
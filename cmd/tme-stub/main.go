@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/Financial-Times/v1-orgs-transformer/tmestub"
+	"github.com/jawher/mow.cli"
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	app := cli.App("tme-stub", "Serves TME taxonomy pages from fixture files for local development")
+	port := app.Int(cli.IntOpt{
+		Name:   "port",
+		Value:  8081,
+		Desc:   "Port to listen on",
+		EnvVar: "PORT",
+	})
+	username := app.String(cli.StringOpt{
+		Name:   "tme-username",
+		Value:  "",
+		Desc:   "Username required for http basic authentication, none if empty",
+		EnvVar: "TME_USERNAME",
+	})
+	password := app.String(cli.StringOpt{
+		Name:   "tme-password",
+		Value:  "",
+		Desc:   "Password required for http basic authentication",
+		EnvVar: "TME_PASSWORD",
+	})
+	fixtures := app.Strings(cli.StringsArg{
+		Name:  "FIXTURE",
+		Value: []string{"tmestub/fixtures/organisations.xml"},
+		Desc:  "Taxonomy XML files to serve the terms of",
+	})
+	app.Spec = "[OPTIONS] [FIXTURE...]"
+
+	app.Action = func() {
+		stub, err := tmestub.New(*fixtures...)
+		if err != nil {
+			log.Fatalf("Error loading fixtures: %v", err)
+		}
+		stub.WithBasicAuth(*username, *password)
+		log.Printf("Serving %d terms from %v on port %d", stub.Count(), *fixtures, *port)
+		if err := http.ListenAndServe(fmt.Sprintf(":%d", *port), stub); err != nil {
+			log.Errorf("Error by listen and serve: %v", err.Error())
+		}
+	}
+	app.Run(os.Args)
+}
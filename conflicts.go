@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+// uuidConflict lists every org transformed to the same UUID during a load. The first entry is the one kept in the cache.
+type uuidConflict struct {
+	UUID string `json:"uuid"`
+	Orgs []org  `json:"orgs"`
+}
+
+type conflictReport struct {
+	sync.RWMutex
+	// orgs by UUID, then by their JSON encoding so an org fetched again is only reported once
+	conflicts map[string]map[string]org
+	// encodings of the orgs that found another one staged. Writers report in no set order, so an org may already be
+	// listed as the staged one of a later write when it is reported as arriving.
+	arrived map[string]bool
+}
+
+func newConflictReport() *conflictReport {
+	return &conflictReport{conflicts: make(map[string]map[string]org), arrived: make(map[string]bool)}
+}
+
+// add records that other arrived with the UUID of the staged org and tells whether other had not arrived yet
+func (r *conflictReport) add(staged org, other org) bool {
+	r.Lock()
+	defer r.Unlock()
+	orgs, ok := r.conflicts[staged.UUID]
+	if !ok {
+		orgs = make(map[string]org)
+		r.conflicts[staged.UUID] = orgs
+	}
+	orgs[string(encodeOrg(staged))] = staged
+	key := string(encodeOrg(other))
+	orgs[key] = other
+	if r.arrived[key] {
+		return false
+	}
+	r.arrived[key] = true
+	return true
+}
+
+func (r *conflictReport) list() []uuidConflict {
+	r.RLock()
+	defer r.RUnlock()
+	list := make([]uuidConflict, 0, len(r.conflicts))
+	for u, orgs := range r.conflicts {
+		c := uuidConflict{UUID: u}
+		for _, o := range orgs {
+			c.Orgs = append(c.Orgs, o)
+		}
+		sort.Slice(c.Orgs, func(i, j int) bool { return keptOver(c.Orgs[i], c.Orgs[j]) })
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].UUID < list[j].UUID })
+	return list
+}
+
+// keptOver tells whether a is kept in the cache rather than b when both have the same UUID, whatever order the pages were
+// written in. UUIDs are derived from the TME identifier, so colliding orgs share it and the lowest JSON encoding wins.
+func keptOver(a org, b org) bool {
+	return bytes.Compare(encodeOrg(a), encodeOrg(b)) < 0
+}
+
+func encodeOrg(o org) []byte {
+	// an org only holds strings, so encoding it cannot fail
+	encoded, _ := json.Marshal(o)
+	return encoded
+}
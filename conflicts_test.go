@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConflictReportAddInAnyOrder(t *testing.T) {
+	assert := assert.New(t)
+	x, y, z := org{UUID: testUUID, PrefLabel: "X"}, org{UUID: testUUID, PrefLabel: "Y"}, org{UUID: testUUID, PrefLabel: "Z"}
+	report := newConflictReport()
+
+	// Y replaced X, then Z found Y staged, but the writer of Z reported first
+	assert.True(report.add(y, z))
+	assert.True(report.add(x, y), "An org listed as staged should still be reported as arriving")
+	assert.False(report.add(y, z), "An org fetched again should only be reported once")
+
+	conflicts := report.list()
+	if assert.Len(conflicts, 1) {
+		assert.Equal([]org{x, y, z}, conflicts[0].Orgs)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import "fmt"
+
+// countDropGuard stops a load replacing the cache when it brings back suspiciously fewer orgs than the previous one.
+// A zero limit disables that check.
+type countDropGuard struct {
+	maxDrop        int
+	maxDropPercent int
+}
+
+func (g countDropGuard) check(previous int, loaded int) error {
+	drop := previous - loaded
+	if drop <= 0 {
+		return nil
+	}
+	if g.maxDrop > 0 && drop > g.maxDrop {
+		return fmt.Errorf("Load aborted as TME returned %d orgs against %d previously: a drop of %d exceeds the limit of %d. Keeping the previous data", loaded, previous, drop, g.maxDrop)
+	}
+	if g.maxDropPercent > 0 && drop*100 > g.maxDropPercent*previous {
+		return fmt.Errorf("Load aborted as TME returned %d orgs against %d previously: a drop of %d%% exceeds the limit of %d%%. Keeping the previous data", loaded, previous, drop*100/previous, g.maxDropPercent)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountDropGuard(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name     string
+		guard    countDropGuard
+		previous int
+		loaded   int
+		aborted  bool
+	}{
+		{"Disabled", countDropGuard{}, 1000, 1, false},
+		{"First load", countDropGuard{maxDrop: 10, maxDropPercent: 10}, 0, 500, false},
+		{"Growth", countDropGuard{maxDrop: 10, maxDropPercent: 10}, 500, 600, false},
+		{"Absolute drop within limit", countDropGuard{maxDrop: 10}, 1000, 990, false},
+		{"Absolute drop over limit", countDropGuard{maxDrop: 10}, 1000, 989, true},
+		{"Percentage drop within limit", countDropGuard{maxDropPercent: 10}, 1000, 900, false},
+		{"Percentage drop over limit", countDropGuard{maxDropPercent: 10}, 1000, 899, true},
+		{"Empty load", countDropGuard{maxDropPercent: 10}, 1000, 0, true},
+	}
+
+	for _, test := range tests {
+		err := test.guard.check(test.previous, test.loaded)
+		assert.Equal(test.aborted, err != nil, fmt.Sprintf("%s: Unexpected result %v", test.name, err))
+	}
+}
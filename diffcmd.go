@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/boltdb/bolt"
+	"github.com/jawher/mow.cli"
+	log "github.com/sirupsen/logrus"
+)
+
+type fieldChange struct {
+	Field   string   `json:"field"`
+	Old     string   `json:"old,omitempty"`
+	New     string   `json:"new,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+type orgChange struct {
+	UUID      string        `json:"uuid"`
+	PrefLabel string        `json:"prefLabel"`
+	Changes   []fieldChange `json:"changes"`
+}
+
+type cacheDiff struct {
+	Added   []org       `json:"added"`
+	Removed []org       `json:"removed"`
+	Changed []orgChange `json:"changed"`
+}
+
+// diffCommand compares the orgs of two cache files by UUID
+func diffCommand(cmd *cli.Cmd) {
+	format := cmd.String(cli.StringOpt{
+		Name:  "format",
+		Value: "human",
+		Desc:  "Output format, human or json",
+	})
+	oldFile := cmd.StringArg("OLD", "", "Cache file before the change")
+	newFile := cmd.StringArg("NEW", "", "Cache file after the change")
+	cmd.Spec = "[--format] OLD NEW"
+
+	cmd.Action = func() {
+		if *format != "human" && *format != "json" {
+			log.Fatalf("Unknown format [%v], expected human or json", *format)
+		}
+		oldOrgs, err := readCacheFile(*oldFile)
+		if err != nil {
+			log.Fatalf("Error reading [%v]: %v", *oldFile, err)
+		}
+		newOrgs, err := readCacheFile(*newFile)
+		if err != nil {
+			log.Fatalf("Error reading [%v]: %v", *newFile, err)
+		}
+		if err := writeDiff(diffCaches(oldOrgs, newOrgs), *format, os.Stdout); err != nil {
+			log.Fatalf("Error writing diff: %v", err)
+		}
+	}
+}
+
+func readCacheFile(path string) (map[string]org, error) {
+	db, err := openCacheReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return readCachedOrgs(db)
+}
+
+func readCachedOrgs(db *bolt.DB) (map[string]org, error) {
+	orgs := make(map[string]org)
+	err := forEachCachedOrg(db, func(k []byte, v []byte) error {
+		var anOrg org
+		if err := json.Unmarshal(v, &anOrg); err != nil {
+			return fmt.Errorf("Could not unmarshal cached organisation [%s]: %v", k, err)
+		}
+		orgs[string(k)] = anOrg
+		return nil
+	})
+	return orgs, err
+}
+
+func diffCaches(oldOrgs map[string]org, newOrgs map[string]org) cacheDiff {
+	d := cacheDiff{Added: []org{}, Removed: []org{}, Changed: []orgChange{}}
+	for _, u := range sortedUUIDs(newOrgs) {
+		oldOrg, ok := oldOrgs[u]
+		if !ok {
+			d.Added = append(d.Added, newOrgs[u])
+			continue
+		}
+		if changes := diffOrgs(oldOrg, newOrgs[u]); len(changes) > 0 {
+			d.Changed = append(d.Changed, orgChange{UUID: u, PrefLabel: newOrgs[u].PrefLabel, Changes: changes})
+		}
+	}
+	for _, u := range sortedUUIDs(oldOrgs) {
+		if _, ok := newOrgs[u]; !ok {
+			d.Removed = append(d.Removed, oldOrgs[u])
+		}
+	}
+	return d
+}
+
+func sortedUUIDs(orgs map[string]org) []string {
+	uuids := make([]string, 0, len(orgs))
+	for u := range orgs {
+		uuids = append(uuids, u)
+	}
+	sort.Strings(uuids)
+	return uuids
+}
+
+// diffOrgs gives the label and alias differences between two versions of an org
+func diffOrgs(oldOrg org, newOrg org) []fieldChange {
+	var changes []fieldChange
+	if oldOrg.PrefLabel != newOrg.PrefLabel {
+		changes = append(changes, fieldChange{Field: "prefLabel", Old: oldOrg.PrefLabel, New: newOrg.PrefLabel})
+	}
+	if oldOrg.ProperName != newOrg.ProperName {
+		changes = append(changes, fieldChange{Field: "properName", Old: oldOrg.ProperName, New: newOrg.ProperName})
+	}
+	added, removed := difference(newOrg.Aliases, oldOrg.Aliases), difference(oldOrg.Aliases, newOrg.Aliases)
+	if len(added) > 0 || len(removed) > 0 {
+		changes = append(changes, fieldChange{Field: "aliases", Added: added, Removed: removed})
+	}
+	return changes
+}
+
+// difference gives the elements of a missing from b
+func difference(a []string, b []string) []string {
+	inB := make(map[string]bool)
+	for _, v := range b {
+		inB[v] = true
+	}
+	var diff []string
+	for _, v := range a {
+		if !inB[v] {
+			diff = append(diff, v)
+		}
+	}
+	return diff
+}
+
+func writeDiff(d cacheDiff, format string, w io.Writer) error {
+	if format == "json" {
+		return json.NewEncoder(w).Encode(d)
+	}
+	for _, o := range d.Added {
+		fmt.Fprintf(w, "+ %v %q\n", o.UUID, o.PrefLabel)
+	}
+	for _, o := range d.Removed {
+		fmt.Fprintf(w, "- %v %q\n", o.UUID, o.PrefLabel)
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(w, "~ %v %q\n", c.UUID, c.PrefLabel)
+		for _, f := range c.Changes {
+			if f.Field == "aliases" {
+				fmt.Fprintf(w, "    %v: added %q, removed %q\n", f.Field, f.Added, f.Removed)
+				continue
+			}
+			fmt.Fprintf(w, "    %v: %q -> %q\n", f.Field, f.Old, f.New)
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d added, %d removed, %d changed\n", len(d.Added), len(d.Removed), len(d.Changed))
+	return err
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffCaches(t *testing.T) {
+	assert := assert.New(t)
+	oldOrgs := map[string]org{
+		"1": org{UUID: "1", PrefLabel: "European Union", ProperName: "European Union", Aliases: []string{"EU", "EEC"}},
+		"2": org{UUID: "2", PrefLabel: "Bank of England", ProperName: "Bank of England"},
+		"3": org{UUID: "3", PrefLabel: "IMF", ProperName: "IMF"},
+	}
+	newOrgs := map[string]org{
+		"1": org{UUID: "1", PrefLabel: "The European Union", ProperName: "European Union", Aliases: []string{"EU", "The EU"}},
+		"2": org{UUID: "2", PrefLabel: "Bank of England", ProperName: "Bank of England"},
+		"4": org{UUID: "4", PrefLabel: "Financial Times", ProperName: "Financial Times"},
+	}
+
+	d := diffCaches(oldOrgs, newOrgs)
+
+	assert.Equal([]org{newOrgs["4"]}, d.Added)
+	assert.Equal([]org{oldOrgs["3"]}, d.Removed)
+	assert.Equal([]orgChange{orgChange{UUID: "1", PrefLabel: "The European Union", Changes: []fieldChange{
+		fieldChange{Field: "prefLabel", Old: "European Union", New: "The European Union"},
+		fieldChange{Field: "aliases", Added: []string{"The EU"}, Removed: []string{"EEC"}},
+	}}}, d.Changed)
+}
+
+func TestWriteDiff(t *testing.T) {
+	assert := assert.New(t)
+	d := cacheDiff{
+		Added:   []org{org{UUID: "4", PrefLabel: "Financial Times"}},
+		Removed: []org{},
+		Changed: []orgChange{orgChange{UUID: "1", PrefLabel: "EU", Changes: []fieldChange{fieldChange{Field: "prefLabel", Old: "European Union", New: "EU"}}}},
+	}
+
+	var human bytes.Buffer
+	assert.NoError(writeDiff(d, "human", &human))
+	assert.Equal("+ 4 \"Financial Times\"\n~ 1 \"EU\"\n    prefLabel: \"European Union\" -> \"EU\"\n1 added, 0 removed, 1 changed\n", human.String())
+
+	var out bytes.Buffer
+	assert.NoError(writeDiff(d, "json", &out))
+	var decoded cacheDiff
+	assert.NoError(json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(d, decoded)
+}
+
+func TestReadCachedOrgs(t *testing.T) {
+	assert := assert.New(t)
+	db := writeTestCache(assert, testCacheFile(t), map[string][]byte{europeanUnionUUID: europeanUnion()})
+	defer db.Close()
+
+	orgs, err := readCachedOrgs(db)
+	assert.NoError(err)
+	assert.Equal("European Union", orgs[europeanUnionUUID].PrefLabel)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Financial-Times/tme-reader/tmereader"
+	"github.com/Financial-Times/v1-orgs-transformer/tmestub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndToEndAgainstTMEStub(t *testing.T) {
+	assert := assert.New(t)
+	stub, err := tmestub.New("tmestub/fixtures/organisations.xml")
+	assert.NoError(err)
+	tme := httptest.NewServer(stub.WithBasicAuth("user", "pass"))
+	defer tme.Close()
+
+	repo := tmereader.NewTmeRepository(http.DefaultClient, tme.URL, "user", "pass", "token", 2, 1, "ON", &tmereader.AuthorityFiles{}, new(orgTransformer))
+	service := newOrgService(repo, "http://localhost:8080/transformers/organisations/", "ON", 2, testCacheFile(t), 10, defaultValidation(), countDropGuard{}, pageRetryPolicy{}, pipelineConfig{})
+	defer service.shutdown(context.Background())
+	waitForLoad(t, service)
+	assert.True(service.isInitialised(), "Service should load every page from the stub")
+
+	auth, err := newAdminAuth([]string{"e2e:secret"}, "", false)
+	assert.NoError(err)
+	limits, err := parseRateLimits([]string{"/transformers/organisations/{uuid}=1/1"})
+	assert.NoError(err)
+	h := serviceHandler(service, auth, newRateLimiter(limits, nil), "_ft/api.yml")
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req.RemoteAddr = "192.0.2.1:1234"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(newRequest("GET", "/transformers/organisations/__count"))
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal(fmt.Sprint(stub.Count()), rec.Body.String(), "Wrong count")
+
+	rec = serve(newRequest("GET", "/transformers/organisations/__ids"))
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal(stub.Count(), strings.Count(rec.Body.String(), "\n"), "Wrong number of IDs")
+
+	expected := transformOrg(term{CanonicalName: "Financial Times", RawID: "ODNlYWM2ZDYtMzU1Zi00ZWFkLWE0YjEtNGNmODRmYWU4MDg3-T04=", Aliases: aliases{Alias: []alias{alias{Name: "FT"}, alias{Name: "The Financial Times"}}}}, "ON")
+	rec = serve(newRequest("GET", "/transformers/organisations/"+expected.UUID))
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Contains(rec.Body.String(), `"prefLabel":"Financial Times"`)
+	assert.Contains(rec.Body.String(), `"aliases":["FT","The Financial Times","Financial Times"]`)
+	rec = serve(newRequest("GET", "/transformers/organisations/"+expected.UUID))
+	assert.Equal(http.StatusTooManyRequests, rec.Code, "Requests over the route limit should be rejected")
+
+	rec = serve(newRequest("GET", "/transformers/organisations/not-a-uuid"))
+	assert.Equal(http.StatusNotFound, rec.Code, "Only UUIDs should be routed to an organisation")
+
+	rec = serve(newRequest("POST", "/transformers/organisations/__reload"))
+	assert.Equal(http.StatusUnauthorized, rec.Code, "Reloads should require an admin key")
+	req := newRequest("POST", "/transformers/organisations/__reload")
+	req.Header.Set(apiKeyHeader, "secret")
+	rec = serve(req)
+	assert.Equal(http.StatusAccepted, rec.Code, "Reloads with an admin key should start")
+	waitForLoad(t, service)
+}
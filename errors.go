@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// Machine-readable codes of the error responses
+const (
+	codeNotLoaded        = "NOT_LOADED"
+	codeNotFound         = "NOT_FOUND"
+	codeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+	codeInvalidFields    = "INVALID_FIELDS"
+	codeInvalidFilter    = "INVALID_FILTER"
+	codeInvalidPage      = "INVALID_PAGE"
+	codeInternal         = "INTERNAL_ERROR"
+	codeNothingToResume  = "NOTHING_TO_RESUME"
+	codeLoadRunning      = "LOAD_RUNNING"
+	codeLoadNotRunning   = "LOAD_NOT_RUNNING"
+	codeUnavailable      = "UNAVAILABLE"
+	codeUnauthorized     = "UNAUTHORIZED"
+	codeForbidden        = "FORBIDDEN"
+	codeRateLimited      = "RATE_LIMITED"
+)
+
+// errorResponse is the body of every error response
+type errorResponse struct {
+	Code          string `json:"code"`
+	Message       string `json:"message"`
+	TransactionID string `json:"transactionId,omitempty"`
+}
+
+// transactionID gives the ID the request logging handler put on the response, or else the one of the request
+func transactionID(w http.ResponseWriter, r *http.Request) string {
+	if tid := w.Header().Get(transactionidutils.TransactionIDHeader); tid != "" {
+		return tid
+	}
+	return transactionidutils.GetTransactionIDFromRequest(r)
+}
+
+func writeJSONError(w http.ResponseWriter, r *http.Request, statusCode int, code string, msg string) {
+	writeJSON(w, statusCode, errorResponse{Code: code, Message: msg, TransactionID: transactionID(w, r)})
+}
+
+// notFound answers requests to paths the router has no route for
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeJSONError(w, r, http.StatusNotFound, codeNotFound, fmt.Sprintf("No endpoint at %v", r.URL.Path))
+}
+
+// methodNotAllowed answers requests to routes that do not take their method
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeJSONError(w, r, http.StatusMethodNotAllowed, codeMethodNotAllowed, fmt.Sprintf("Method %v not allowed at %v", r.Method, r.URL.Path))
+}
+
+// writeJSON encodes obj before writing anything, so an encoding failure can still be answered with a 500
+func writeJSON(w http.ResponseWriter, statusCode int, obj interface{}) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(obj); err != nil {
+		log.Errorf("Error on json encoding=%v", err)
+		statusCode = http.StatusInternalServerError
+		body.Reset()
+		json.NewEncoder(&body).Encode(errorResponse{Code: codeInternal, Message: "Error encoding the response", TransactionID: w.Header().Get(transactionidutils.TransactionIDHeader)})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(body.Bytes())
+}
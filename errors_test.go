@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteJSONEncodingFailure(t *testing.T) {
+	assert := assert.New(t)
+	rec := httptest.NewRecorder()
+	rec.Header().Set("X-Request-Id", testTransactionID)
+	writeJSON(rec, http.StatusOK, map[string]interface{}{"unencodable": make(chan int)})
+
+	assert.Equal(http.StatusInternalServerError, rec.Code)
+	assert.Equal("application/json", rec.Header().Get("Content-Type"))
+	var body errorResponse
+	assert.NoError(json.Unmarshal(rec.Body.Bytes(), &body), "Body should be valid JSON")
+	assert.Equal(errorResponse{Code: codeInternal, Message: "Error encoding the response", TransactionID: testTransactionID}, body)
+}
+
+func TestErrorResponsesOfRouter(t *testing.T) {
+	assert := assert.New(t)
+	auth, err := newAdminAuth([]string{"ops:s3cret"}, "", false)
+	assert.NoError(err)
+	h := serviceHandler(&dummyService{initialised: true}, auth, newRateLimiter(nil, nil), "_ft/api.yml")
+	tests := []struct {
+		name       string
+		req        *http.Request
+		statusCode int
+		body       string
+	}{
+		{"Unknown path", newRequest("GET", "/transformers/people"), http.StatusNotFound, errorBody(codeNotFound, "No endpoint at /transformers/people")},
+		{"Organisation ID not a UUID", newRequest("GET", "/transformers/organisations/eu"), http.StatusNotFound, errorBody(codeNotFound, "No endpoint at /transformers/organisations/eu")},
+		{"Method not allowed", newRequest("PUT", "/transformers/organisations/__reload"), http.StatusMethodNotAllowed, errorBody(codeMethodNotAllowed, "Method PUT not allowed at /transformers/organisations/__reload")},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, test.req)
+		assert.Equal(test.statusCode, rec.Code, test.name)
+		assert.Equal("application/json", rec.Header().Get("Content-Type"), test.name)
+		assert.Equal(test.body, rec.Body.String(), test.name)
+	}
+}
+
+func TestErrorResponsesOfMiddleware(t *testing.T) {
+	assert := assert.New(t)
+	auth, err := newAdminAuth([]string{"ops:s3cret"}, "", false)
+	assert.NoError(err)
+	rec := httptest.NewRecorder()
+	auth.protect(http.NotFoundHandler()).ServeHTTP(rec, newRequest("POST", "/transformers/organisations/__reload"))
+
+	assert.Equal(http.StatusUnauthorized, rec.Code)
+	assert.Equal(errorBody(codeUnauthorized, "Unauthorized"), rec.Body.String())
+	assert.Equal(apiKeyHeader, rec.Header().Get("WWW-Authenticate"))
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// orgFieldNames are the JSON names of the org fields that can be selected, in the order the org encodes them
+var orgFieldNames = jsonFieldNames(reflect.TypeOf(org{}))
+
+func jsonFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseFields reads a comma-separated list of org field names, an empty list selecting every field
+func parseFields(param string) ([]string, error) {
+	var fields, unknown []string
+	for _, f := range strings.Split(param, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if !contains(orgFieldNames, f) {
+			unknown = append(unknown, f)
+			continue
+		}
+		fields = append(fields, f)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("Unknown fields %v, expected some of %v", strings.Join(unknown, ","), strings.Join(orgFieldNames, ","))
+	}
+	return fields, nil
+}
+
+// selectFields keeps the given fields of an org, or all of them when none is given. Empty fields left out of the org stay left out.
+func selectFields(o org, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return o, nil
+	}
+	encoded, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	return selectEncodedFields(encoded, fields)
+}
+
+func selectEncodedFields(encoded []byte, fields []string) (selectedFields, error) {
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &all); err != nil {
+		return nil, err
+	}
+	selected := make(selectedFields, len(fields))
+	for _, f := range fields {
+		if v, ok := all[f]; ok {
+			selected[f] = v
+		}
+	}
+	return selected, nil
+}
+
+// selectedFields are encoded in the order of orgFieldNames, as the whole org would be, rather than alphabetically as maps are
+type selectedFields map[string]json.RawMessage
+
+func (s selectedFields) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for _, name := range orgFieldNames {
+		v, ok := s[name]
+		if !ok {
+			continue
+		}
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
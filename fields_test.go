@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFields(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name   string
+		param  string
+		fields []string
+		err    bool
+	}{
+		{"Empty", "", nil, false},
+		{"Single field", "uuid", []string{"uuid"}, false},
+		{"Several fields with spaces", "uuid, prefLabel,,aliases", []string{"uuid", "prefLabel", "aliases"}, false},
+		{"Unknown field", "uuid,label", nil, true},
+		{"Field of an alternative identifier", "TME", nil, true},
+	}
+	for _, test := range tests {
+		fields, err := parseFields(test.param)
+		assert.Equal(test.err, err != nil, test.name)
+		assert.Equal(test.fields, fields, test.name)
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	assert := assert.New(t)
+	anOrg := org{UUID: testUUID, PrefLabel: "European Union", Type: "Organisation"}
+	tests := []struct {
+		name   string
+		fields []string
+		json   string
+	}{
+		{"Every field", nil, `{"uuid":"` + testUUID + `","properName":"","prefLabel":"European Union","type":"Organisation","alternativeIdentifiers":{}}`},
+		{"Some fields in the order of the org", []string{"prefLabel", "uuid"}, `{"uuid":"` + testUUID + `","prefLabel":"European Union"}`},
+		{"Empty field left out", []string{"uuid", "aliases"}, `{"uuid":"` + testUUID + `"}`},
+	}
+	for _, test := range tests {
+		selected, err := selectFields(anOrg, test.fields)
+		assert.NoError(err, test.name)
+		out, _ := json.Marshal(selected)
+		assert.Equal(test.json, string(out), test.name)
+	}
+}
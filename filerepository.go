@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Financial-Times/tme-reader/tmereader"
+)
+
+const fileSourcePrefix = "file:"
+
+// fileRepository serves the terms of local taxonomy XML files, such as TME exports, as if they came from TME.
+// The files are read again when a load starts, through refresh, so every page of a load comes from the same snapshot.
+type fileRepository struct {
+	sync.RWMutex
+	paths       []string
+	maxRecords  int
+	transformer *orgTransformer
+	terms       []interface{}
+}
+
+// newFileRepository reads the taxonomy files at path: a comma-separated list of files, directories of .xml files or glob patterns
+func newFileRepository(path string, maxRecords int, transformer *orgTransformer) (tmereader.Repository, error) {
+	r := &fileRepository{paths: strings.Split(path, ","), maxRecords: maxRecords, transformer: transformer}
+	if err := r.read(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *fileRepository) files() ([]string, error) {
+	var files []string
+	for _, p := range r.paths {
+		info, err := os.Stat(p)
+		if err == nil && info.IsDir() {
+			matches, _ := filepath.Glob(filepath.Join(p, "*.xml"))
+			sort.Strings(matches)
+			files = append(files, matches...)
+			continue
+		}
+		if err == nil {
+			files = append(files, p)
+			continue
+		}
+		matches, globErr := filepath.Glob(p)
+		if globErr != nil || len(matches) == 0 {
+			return nil, fmt.Errorf("No taxonomy file found at [%v]", p)
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("No taxonomy file found at %v", r.paths)
+	}
+	return files, nil
+}
+
+func (r *fileRepository) read() error {
+	files, err := r.files()
+	if err != nil {
+		return err
+	}
+	var terms []interface{}
+	for _, f := range files {
+		contents, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		fileTerms, err := r.transformer.UnMarshallTaxonomy(contents)
+		if err != nil {
+			return fmt.Errorf("Could not parse taxonomy file [%v]: %v", f, err)
+		}
+		terms = append(terms, fileTerms...)
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.terms = terms
+	return nil
+}
+
+// refresh reads the files again, before any page of a new load is fetched
+func (r *fileRepository) refresh() error {
+	return r.read()
+}
+
+func (r *fileRepository) GetTmeTermsFromIndex(startRecord int) ([]interface{}, error) {
+	r.RLock()
+	defer r.RUnlock()
+	if startRecord >= len(r.terms) {
+		return []interface{}{}, nil
+	}
+	end := startRecord + r.maxRecords
+	if end > len(r.terms) {
+		end = len(r.terms)
+	}
+	return r.terms[startRecord:end], nil
+}
+
+func (r *fileRepository) GetTmeTermById(id string) (interface{}, error) {
+	r.RLock()
+	defer r.RUnlock()
+	for _, t := range r.terms {
+		if t.(term).RawID == id {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("Term [%v] not found", id)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/tme-reader/tmereader"
+	"github.com/stretchr/testify/assert"
+)
+
+const fixture = "tmestub/fixtures/organisations.xml"
+
+func TestFileRepositoryPaging(t *testing.T) {
+	assert := assert.New(t)
+	repo, err := newFileRepository(fixture, 2, new(orgTransformer))
+	assert.NoError(err)
+
+	tests := []struct {
+		offset int
+		terms  int
+	}{
+		{0, 2}, {2, 2}, {4, 1}, {6, 0},
+	}
+	for _, test := range tests {
+		terms, err := repo.GetTmeTermsFromIndex(test.offset)
+		assert.NoError(err)
+		assert.Len(terms, test.terms, fmt.Sprintf("Wrong number of terms at offset %d", test.offset))
+	}
+
+	found, err := repo.GetTmeTermById("Nstein_GL_US_NY_Municipality_942968")
+	assert.NoError(err)
+	assert.Equal("European Union", found.(term).CanonicalName)
+	_, err = repo.GetTmeTermById("unknown")
+	assert.Error(err)
+}
+
+func TestFileRepositoryPaths(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "taxonomies")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	contents, _ := ioutil.ReadFile(fixture)
+	ioutil.WriteFile(filepath.Join(dir, "a.xml"), contents, 0600)
+	ioutil.WriteFile(filepath.Join(dir, "b.xml"), contents, 0600)
+	ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a taxonomy"), 0600)
+
+	tests := []struct {
+		name  string
+		path  string
+		terms int
+	}{
+		{"Single file", fixture, 5},
+		{"Directory", dir, 10},
+		{"Glob", filepath.Join(dir, "a*.xml"), 5},
+		{"List", fixture + "," + filepath.Join(dir, "b.xml"), 10},
+	}
+	for _, test := range tests {
+		repo, err := newFileRepository(test.path, 100, new(orgTransformer))
+		assert.NoError(err, fmt.Sprintf("%s: Unexpected error", test.name))
+		terms, _ := repo.GetTmeTermsFromIndex(0)
+		assert.Len(terms, test.terms, fmt.Sprintf("%s: Wrong number of terms", test.name))
+	}
+
+	_, err = newFileRepository(filepath.Join(dir, "missing.xml"), 100, new(orgTransformer))
+	assert.Error(err, "Missing file should be rejected")
+	_, err = newFileRepository(filepath.Join(dir, "notes.txt"), 100, new(orgTransformer))
+	assert.Error(err, "Invalid XML should be rejected")
+}
+
+func TestFileRepositoryRereadsOnNewLoad(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "taxonomies")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	contents, _ := ioutil.ReadFile(fixture)
+	ioutil.WriteFile(filepath.Join(dir, "a.xml"), contents, 0600)
+
+	repo, err := newFileRepository(dir, 100, new(orgTransformer))
+	assert.NoError(err)
+	ioutil.WriteFile(filepath.Join(dir, "b.xml"), contents, 0600)
+
+	terms, _ := repo.GetTmeTermsFromIndex(0)
+	assert.Len(terms, 5, "Pages should come from the snapshot of the running load")
+	assert.NoError(refreshRepository(instrumentedRepository{repo}))
+	terms, _ = repo.GetTmeTermsFromIndex(0)
+	assert.Len(terms, 10, "Files added since the last load should be read")
+}
+
+func TestFileRepositoryReadOncePerLoad(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	contents, _ := ioutil.ReadFile(fixture)
+	ioutil.WriteFile(filepath.Join(dir, "a.xml"), contents, 0600)
+	repo, err := newFileRepository(dir, 1, new(orgTransformer))
+	assert.NoError(err)
+	// the first page is the slowest, so the other fetchers run ahead of it
+	slow := &slowFirstPageRepository{Repository: repo, delay: 50 * time.Millisecond}
+	s := &orgServiceImpl{repository: slow, taxonomyName: "ON", maxTmeRecords: 1, cacheFileName: testCacheFile(t),
+		orgCache: newOrgLRU(0), conflicts: newConflictReport(), validation: defaultValidation(), pipeline: pipelineConfig{fetchers: 8}}
+	defer s.shutdown(context.Background())
+	assert.NoError(s.init())
+	count, _ := s.orgCount(context.Background())
+	assert.Equal(5, count)
+
+	ioutil.WriteFile(filepath.Join(dir, "b.xml"), []byte("<taxonomy><term><name>European Central Bank</name><id>ECB</id></term></taxonomy>"), 0600)
+	ctx, l, err := s.startLoad(context.Background())
+	assert.NoError(err)
+	assert.NoError(s.initFrom(ctx, l, 0, false))
+	count, _ = s.orgCount(context.Background())
+	assert.Equal(6, count, "Every page of the reload should come from the files as they are when it starts")
+}
+
+// slowFirstPageRepository delays the first page
+type slowFirstPageRepository struct {
+	tmereader.Repository
+	delay time.Duration
+}
+
+func (r *slowFirstPageRepository) GetTmeTermsFromIndex(startRecord int) ([]interface{}, error) {
+	if startRecord == 0 {
+		time.Sleep(r.delay)
+	}
+	return r.Repository.GetTmeTermsFromIndex(startRecord)
+}
+
+func (r *slowFirstPageRepository) refresh() error {
+	return refreshRepository(r.Repository)
+}
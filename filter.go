@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// orgFilter selects the orgs listed by getOrgs, the zero value selecting all of them
+type orgFilter struct {
+	alias        string
+	tmeIDPrefix  string
+	orgType      string
+	changedSince time.Time
+}
+
+// parseOrgFilter reads the hasAlias, tmeIdPrefix, type and changedSince (RFC 3339) query parameters
+func parseOrgFilter(query url.Values) (orgFilter, error) {
+	f := orgFilter{
+		alias:       query.Get("hasAlias"),
+		tmeIDPrefix: query.Get("tmeIdPrefix"),
+		orgType:     query.Get("type"),
+	}
+	if since := query.Get("changedSince"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return orgFilter{}, fmt.Errorf("Invalid changedSince [%v], expected an RFC 3339 time such as 2017-01-31T15:04:05Z", since)
+		}
+		f.changedSince = t
+	}
+	return f, nil
+}
+
+func (f orgFilter) isEmpty() bool {
+	return f == orgFilter{}
+}
+
+// matches tells whether an org, last changed at the given time, passes every filter set
+func (f orgFilter) matches(o org, changed time.Time) bool {
+	if f.alias != "" && !hasAlias(o, f.alias) {
+		return false
+	}
+	if f.tmeIDPrefix != "" && !hasTMEIDPrefix(o, f.tmeIDPrefix) {
+		return false
+	}
+	if f.orgType != "" && o.Type != f.orgType {
+		return false
+	}
+	if !f.changedSince.IsZero() && changed.Before(f.changedSince) {
+		return false
+	}
+	return true
+}
+
+func hasAlias(o org, alias string) bool {
+	for _, a := range o.Aliases {
+		if strings.EqualFold(a, alias) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTMEIDPrefix(o org, prefix string) bool {
+	for _, id := range o.AlternativeIdentifiers.TME {
+		if strings.HasPrefix(id, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOrgFilter(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name   string
+		query  string
+		filter orgFilter
+		err    bool
+	}{
+		{"No filter", "", orgFilter{}, false},
+		{"Every filter", "hasAlias=EU&tmeIdPrefix=TnN0&type=Organisation&changedSince=2017-01-31T15:04:05Z",
+			orgFilter{alias: "EU", tmeIDPrefix: "TnN0", orgType: "Organisation", changedSince: time.Date(2017, 1, 31, 15, 4, 5, 0, time.UTC)}, false},
+		{"Invalid changedSince", "changedSince=yesterday", orgFilter{}, true},
+	}
+	for _, test := range tests {
+		query, _ := url.ParseQuery(test.query)
+		filter, err := parseOrgFilter(query)
+		assert.Equal(test.err, err != nil, test.name)
+		assert.Equal(test.filter, filter, test.name)
+	}
+}
+
+func TestOrgFilterMatches(t *testing.T) {
+	assert := assert.New(t)
+	changed := time.Date(2017, 1, 31, 15, 4, 5, 0, time.UTC)
+	anOrg := org{Type: "Organisation", Aliases: []string{"European Union", "EU"}, AlternativeIdentifiers: alternativeIdentifiers{TME: []string{"TnN0ZWlu-T04="}}}
+	tests := []struct {
+		name    string
+		filter  orgFilter
+		matches bool
+	}{
+		{"No filter", orgFilter{}, true},
+		{"Alias", orgFilter{alias: "eu"}, true},
+		{"Other alias", orgFilter{alias: "EEC"}, false},
+		{"TME ID prefix", orgFilter{tmeIDPrefix: "TnN0"}, true},
+		{"Other TME ID prefix", orgFilter{tmeIDPrefix: "T04"}, false},
+		{"Type", orgFilter{orgType: "Organisation"}, true},
+		{"Other type", orgFilter{orgType: "Company"}, false},
+		{"Changed at the time", orgFilter{changedSince: changed}, true},
+		{"Changed before the time", orgFilter{changedSince: changed.Add(time.Second)}, false},
+		{"Every filter", orgFilter{alias: "EU", tmeIDPrefix: "TnN0", orgType: "Organisation", changedSince: changed.Add(-time.Hour)}, true},
+		{"Every filter but one", orgFilter{alias: "EU", tmeIDPrefix: "TnN0", orgType: "Company", changedSince: changed.Add(-time.Hour)}, false},
+	}
+	for _, test := range tests {
+		assert.Equal(test.matches, test.filter.matches(anOrg, changed), test.name)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -21,80 +22,111 @@ func newOrgsHandler(service orgsService) orgsHandler {
 }
 
 func (h *orgsHandler) getOrgs(writer http.ResponseWriter, req *http.Request) {
-	if !h.service.isInitialised() {
-		writer.WriteHeader(http.StatusServiceUnavailable)
+	if !h.loaded(writer, req) {
 		return
 	}
 
-	obj, err := h.service.getOrgs()
+	// the list only holds links, so there are no fields to select
+	if _, ok := req.URL.Query()["fields"]; ok {
+		writeJSONError(writer, req, http.StatusBadRequest, codeInvalidFields, "Fields can only be selected when getting an organisation by UUID")
+		return
+	}
+
+	filter, err := parseOrgFilter(req.URL.Query())
+	if err != nil {
+		writeJSONError(writer, req, http.StatusBadRequest, codeInvalidFilter, err.Error())
+		return
+	}
+
+	page, err := parseOrgPage(req.URL.Query())
+	if err != nil {
+		writeJSONError(writer, req, http.StatusBadRequest, codeInvalidPage, err.Error())
+		return
+	}
+
+	obj, next, err := h.service.getOrgs(req.Context(), filter, page)
 	if err != nil {
 		log.Errorf("Error calling getOrgs service: %s", err.Error())
-		writeJSONMessageWithStatus(writer, err.Error(), http.StatusInternalServerError)
+		writeJSONError(writer, req, http.StatusInternalServerError, codeInternal, "Error reading the organisations")
+		return
 	}
-	writeJSONResponse(obj, true, writer)
+	if next != "" {
+		writer.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", nextURL(req.URL, next)))
+	}
+	writeJSON(writer, http.StatusOK, obj)
 }
 
 func (h *orgsHandler) getOrgByUUID(writer http.ResponseWriter, req *http.Request) {
-	if !h.service.isInitialised() {
-		writer.WriteHeader(http.StatusServiceUnavailable)
+	if !h.loaded(writer, req) {
 		return
 	}
 
 	vars := mux.Vars(req)
 	uuid := vars["uuid"]
-
-	obj, found, err := h.service.getOrgByUUID(uuid)
+	fields, err := parseFields(req.URL.Query().Get("fields"))
 	if err != nil {
-		writeJSONMessageWithStatus(writer, err.Error(), http.StatusInternalServerError)
+		writeJSONError(writer, req, http.StatusBadRequest, codeInvalidFields, err.Error())
+		return
 	}
-	writeJSONResponse(obj, found, writer)
-}
-
-func writeJSONResponse(obj interface{}, found bool, writer http.ResponseWriter) {
-	writer.Header().Add("Content-Type", "application/json")
 
+	obj, found, err := h.service.getOrgByUUID(req.Context(), uuid)
+	if err != nil {
+		log.Errorf("Error calling getOrgByUUID service for [%v]: %s", uuid, err.Error())
+		writeJSONError(writer, req, http.StatusInternalServerError, codeInternal, "Error reading the organisation")
+		return
+	}
 	if !found {
-		writer.WriteHeader(http.StatusNotFound)
+		writeJSONError(writer, req, http.StatusNotFound, codeNotFound, fmt.Sprintf("Organisation %v not found", uuid))
 		return
 	}
-
-	enc := json.NewEncoder(writer)
-	if err := enc.Encode(obj); err != nil {
-		log.Errorf("Error on json encoding=%v\n", err)
-		writeJSONMessageWithStatus(writer, err.Error(), http.StatusInternalServerError)
+	selected, err := selectFields(obj, fields)
+	if err != nil {
+		log.Errorf("Error selecting the fields of [%v]: %s", uuid, err.Error())
+		writeJSONError(writer, req, http.StatusInternalServerError, codeInternal, "Error reading the organisation")
 		return
 	}
+	writeJSON(writer, http.StatusOK, selected)
 }
 
-func writeJSONMessageWithStatus(w http.ResponseWriter, msg string, statusCode int) {
-	w.WriteHeader(statusCode)
-	fmt.Fprintln(w, fmt.Sprintf("{\"message\": \"%s\"}", msg))
+// loaded answers with a 503 until the organisations are loaded
+func (h *orgsHandler) loaded(writer http.ResponseWriter, req *http.Request) bool {
+	if h.service.isInitialised() {
+		return true
+	}
+	writeJSONError(writer, req, http.StatusServiceUnavailable, codeNotLoaded, "Organisations are not loaded yet")
+	return false
+}
+
+type messageResponse struct {
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message"`
 }
 
 // ADMIN HANDLERS
 
 func (h *orgsHandler) getOrgCount(writer http.ResponseWriter, req *http.Request) {
-	if !h.service.isInitialised() {
-		writer.WriteHeader(http.StatusServiceUnavailable)
+	if !h.loaded(writer, req) {
 		return
 	}
 
-	count, err := h.service.orgCount()
+	count, err := h.service.orgCount(req.Context())
 	if err != nil {
 		log.Errorf("Error calling orgCount service: %s", err.Error())
-		writeJSONMessageWithStatus(writer, err.Error(), http.StatusInternalServerError)
+		writeJSONError(writer, req, http.StatusInternalServerError, codeInternal, "Error counting the organisations")
+		return
 	}
 	fmt.Fprint(writer, count)
 }
 
 func (h *orgsHandler) getOrgIds(writer http.ResponseWriter, req *http.Request) {
-	if !h.service.isInitialised() {
-		writer.WriteHeader(http.StatusServiceUnavailable)
+	if !h.loaded(writer, req) {
 		return
 	}
-	orgUUIDs, err := h.service.orgIds()
+	orgUUIDs, err := h.service.orgIds(req.Context())
 	if err != nil {
-		writeJSONMessageWithStatus(writer, err.Error(), http.StatusInternalServerError)
+		log.Errorf("Error calling orgIds service: %s", err.Error())
+		writeJSONError(writer, req, http.StatusInternalServerError, codeInternal, "Error reading the organisation IDs")
+		return
 	}
 
 	writer.Header().Add("Content-Type", "application/json")
@@ -104,14 +136,56 @@ func (h *orgsHandler) getOrgIds(writer http.ResponseWriter, req *http.Request) {
 	}
 }
 
-func (h *orgsHandler) reloadOrgs(writer http.ResponseWriter, req *http.Request) {
+func (h *orgsHandler) getConflicts(writer http.ResponseWriter, req *http.Request) {
+	if !h.loaded(writer, req) {
+		return
+	}
+	writeJSON(writer, http.StatusOK, h.service.uuidConflicts())
+}
+
+func (h *orgsHandler) getValidation(writer http.ResponseWriter, req *http.Request) {
+	if !h.loaded(writer, req) {
+		return
+	}
+	writeJSON(writer, http.StatusOK, h.service.validationSummary())
+}
 
-	go func() {
-		if err := h.service.orgReload(); err != nil {
-			log.Errorf("ERROR reloading cache: %v", err.Error())
+func (h *orgsHandler) reloadOrgs(writer http.ResponseWriter, req *http.Request) {
+	resume := req.URL.Query().Get("resume") == "true"
+	msg := "Reloading V1 organisations"
+	if resume {
+		offset, ok := h.service.resumeOffset()
+		if !ok {
+			writeJSONError(writer, req, http.StatusConflict, codeNothingToResume, errNothingToResume.Error())
+			return
 		}
-	}()
-	writeJSONMessageWithStatus(writer, "Reloading V1 organisations", http.StatusAccepted)
+		msg = fmt.Sprintf("Resuming V1 organisations load from offset %d", offset)
+	}
+
+	// the load outlives the request, but keeps its trace
+	id, err := h.service.orgReload(context.WithoutCancel(req.Context()), resume)
+	switch {
+	case errors.Is(err, errNothingToResume):
+		writeJSONError(writer, req, http.StatusConflict, codeNothingToResume, err.Error())
+		return
+	case errors.Is(err, errLoadRunning):
+		writeJSONError(writer, req, http.StatusConflict, codeLoadRunning, err.Error())
+		return
+	case err != nil:
+		log.Errorf("ERROR reloading cache: %v", err.Error())
+		writeJSONError(writer, req, http.StatusServiceUnavailable, codeUnavailable, err.Error())
+		return
+	}
+	writeJSON(writer, http.StatusAccepted, messageResponse{ID: id, Message: msg})
+}
+
+func (h *orgsHandler) cancelReload(writer http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+	if !h.service.cancelLoad(id) {
+		writeJSONError(writer, req, http.StatusNotFound, codeLoadNotRunning, fmt.Sprintf("No running load %v", id))
+		return
+	}
+	writeJSON(writer, http.StatusAccepted, messageResponse{ID: id, Message: fmt.Sprintf("Cancelling load %v", id)})
 }
 
 func (h *orgsHandler) HealthCheck() fthealth.Check {
@@ -130,6 +204,26 @@ func (h *orgsHandler) HealthCheck() fthealth.Check {
 	}
 }
 
+func (h *orgsHandler) LoadStatusCheck() fthealth.Check {
+	return fthealth.Check{
+		BusinessImpact:   "Organisations served may be incomplete or out of date",
+		Name:             "Check the last load from TME succeeded.",
+		PanicGuide:       "https://sites.google.com/a/ft.com/ft-technology-service-transition/home/run-book-library/v1-people-transformer",
+		Severity:         2,
+		TechnicalSummary: "The last load from TME failed, was aborted or could not fetch or store every page, so it was discarded and the previously loaded organisations, if any, are still served. The output gives the error and the failed page offsets.",
+		Checker: func() (string, error) {
+			status, found := h.service.lastLoadStatus()
+			if !found {
+				return "No load has finished yet", nil
+			}
+			if status.Outcome != loadSucceeded {
+				return status.String(), errors.New(status.String())
+			}
+			return status.String(), nil
+		},
+	}
+}
+
 func (h *orgsHandler) GTG() gtg.Status {
 	return gtg.FailFastParallelCheck([]gtg.StatusChecker{h.gtgCheck})()
 }
@@ -138,5 +232,8 @@ func (h *orgsHandler) gtgCheck() gtg.Status {
 	if h.service.isInitialised() && h.service.isDataLoaded() {
 		return gtg.Status{GoodToGo: true}
 	}
-	return gtg.Status{GoodToGo: false}
+	if status, found := h.service.lastLoadStatus(); found {
+		return gtg.Status{GoodToGo: false, Message: status.String()}
+	}
+	return gtg.Status{GoodToGo: false, Message: "Organisations are not loaded yet"}
 }
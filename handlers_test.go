@@ -1,55 +1,124 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 )
 
 const testUUID = "bba39990-c78d-3629-ae83-808c333c6dbc"
+const testLoadID = "6a1cbb3c-3b3e-4b38-9d3e-4b63ac9ab5e1"
+const testTransactionID = "tid_handlers_test"
 const getOrganisationsResponse = "[{\"apiUrl\":\"http://localhost:8080/transformers/organisations/bba39990-c78d-3629-ae83-808c333c6dbc\"}]\n"
 const getOrganisationByUUIDResponse = "{\"uuid\":\"bba39990-c78d-3629-ae83-808c333c6dbc\",\"properName\":\"European Union\",\"prefLabel\":\"European Union\",\"type\":\"Organisation\",\"alternativeIdentifiers\":{" +
 	"\"TME\":[\"MTE3-U3ViamVjdHM=\"]," +
 	"\"uuids\":[\"bba39990-c78d-3629-ae83-808c333c6dbc\"]" +
 	"}}\n"
 const testIDs = "{\"ID\":\"bba39990-c78d-3629-ae83-808c333c6dbc\"}\n"
+const testConflicts = "[{\"uuid\":\"bba39990-c78d-3629-ae83-808c333c6dbc\",\"orgs\":[" +
+	"{\"uuid\":\"bba39990-c78d-3629-ae83-808c333c6dbc\",\"properName\":\"\",\"prefLabel\":\"\",\"type\":\"\",\"alternativeIdentifiers\":{}}," +
+	"{\"uuid\":\"bba39990-c78d-3629-ae83-808c333c6dbc\",\"properName\":\"\",\"prefLabel\":\"\",\"type\":\"\",\"alternativeIdentifiers\":{}}" +
+	"]}]\n"
+const testValidation = "{\"checked\":1,\"rules\":[{\"rule\":\"empty-name\",\"count\":1,\"sampleUUIDs\":[\"bba39990-c78d-3629-ae83-808c333c6dbc\"]}]}\n"
 
-func TestHandlers(t *testing.T) {
-	assert := assert.New(t)
-	tests := []struct {
-		name         string
-		req          *http.Request
-		dummyService orgsService
-		statusCode   int
-		contentType  string // Contents of the Content-Type header
-		body         string
-	}{
+var notLoaded = errorBody(codeNotLoaded, "Organisations are not loaded yet")
+
+type handlerTest struct {
+	name         string
+	req          *http.Request
+	dummyService orgsService
+	statusCode   int
+	contentType  string // Contents of the Content-Type header
+	body         string
+}
+
+// handlerTests are the requests made to the handlers and the responses expected, also checked against the API spec
+func handlerTests() []handlerTest {
+	return []handlerTest{
 		{"Success - get organisation by uuid", newRequest("GET", fmt.Sprintf("/transformers/organisations/%s", testUUID)), &dummyService{found: true, initialised: true, orgs: []org{org{UUID: testUUID, ProperName: "European Union", PrefLabel: "European Union", AlternativeIdentifiers: alternativeIdentifiers{Uuids: []string{testUUID}, TME: []string{"MTE3-U3ViamVjdHM="}}, Type: "Organisation"}}}, http.StatusOK, "application/json", getOrganisationByUUIDResponse},
-		{"Not found - get organisation by uuid", newRequest("GET", fmt.Sprintf("/transformers/organisations/%s", testUUID)), &dummyService{found: false, initialised: true, orgs: []org{org{}}}, http.StatusNotFound, "application/json", ""},
-		{"Service unavailable - get organisation by uuid", newRequest("GET", fmt.Sprintf("/transformers/organisations/%s", testUUID)), &dummyService{found: false, initialised: false, orgs: []org{}}, http.StatusServiceUnavailable, "application/json", ""},
+		{"Success - get organisation fields by uuid", newRequest("GET", fmt.Sprintf("/transformers/organisations/%s?fields=uuid,prefLabel,aliases", testUUID)), &dummyService{found: true, initialised: true, orgs: []org{org{UUID: testUUID, ProperName: "European Union", PrefLabel: "European Union", Aliases: []string{"EU"}, Type: "Organisation"}}}, http.StatusOK, "application/json", "{\"uuid\":\"" + testUUID + "\",\"prefLabel\":\"European Union\",\"aliases\":[\"EU\"]}\n"},
+		{"Bad request - get unknown organisation fields by uuid", newRequest("GET", fmt.Sprintf("/transformers/organisations/%s?fields=uuid,labels", testUUID)), &dummyService{found: true, initialised: true, orgs: []org{org{UUID: testUUID}}}, http.StatusBadRequest, "application/json", errorBody(codeInvalidFields, "Unknown fields labels, expected some of uuid,properName,prefLabel,type,alternativeIdentifiers,aliases")},
+		{"Not found - get organisation by uuid", newRequest("GET", fmt.Sprintf("/transformers/organisations/%s", testUUID)), &dummyService{found: false, initialised: true, orgs: []org{org{}}}, http.StatusNotFound, "application/json", errorBody(codeNotFound, "Organisation "+testUUID+" not found")},
+		{"Service unavailable - get organisation by uuid", newRequest("GET", fmt.Sprintf("/transformers/organisations/%s", testUUID)), &dummyService{found: false, initialised: false, orgs: []org{}}, http.StatusServiceUnavailable, "application/json", notLoaded},
+		{"Internal error - get organisation by uuid", newRequest("GET", fmt.Sprintf("/transformers/organisations/%s", testUUID)), &dummyService{initialised: true, err: errors.New("cache file corrupt")}, http.StatusInternalServerError, "application/json", errorBody(codeInternal, "Error reading the organisation")},
 		{"Success - get organisations", newRequest("GET", "/transformers/organisations"), &dummyService{found: true, initialised: true, orgs: []org{org{UUID: testUUID}}}, http.StatusOK, "application/json", getOrganisationsResponse},
-		{"Service unavailable - get organisations", newRequest("GET", "/transformers/organisations"), &dummyService{found: false, initialised: false, orgs: []org{}}, http.StatusServiceUnavailable, "application/json", ""},
-		{"Success - get count", newRequest("GET", "/transformers/organisations/__count"), &dummyService{found: true, initialised: true, orgs: []org{org{UUID: testUUID}}}, http.StatusOK, "application/json", "1"},
+		{"Success - get organisations by alias", newRequest("GET", "/transformers/organisations?hasAlias=EU&type=Organisation"), &dummyService{found: true, initialised: true, orgs: []org{org{UUID: testUUID, Type: "Organisation", Aliases: []string{"EU"}}, org{UUID: "6a7edb42-c27a-3186-a0b9-7e3cdc91e16b", Type: "Organisation"}}}, http.StatusOK, "application/json", getOrganisationsResponse},
+		{"Bad request - select fields of organisations", newRequest("GET", "/transformers/organisations?fields=uuid"), &dummyService{found: true, initialised: true, orgs: []org{org{UUID: testUUID}}}, http.StatusBadRequest, "application/json", errorBody(codeInvalidFields, "Fields can only be selected when getting an organisation by UUID")},
+		{"Bad request - get organisations with an invalid limit", newRequest("GET", "/transformers/organisations?limit=0"), &dummyService{found: true, initialised: true, orgs: []org{org{UUID: testUUID}}}, http.StatusBadRequest, "application/json", errorBody(codeInvalidPage, "Invalid limit [0], expected a number from 1 to 10000")},
+		{"Bad request - get organisations changed since an invalid time", newRequest("GET", "/transformers/organisations?changedSince=yesterday"), &dummyService{found: true, initialised: true, orgs: []org{org{UUID: testUUID}}}, http.StatusBadRequest, "application/json", errorBody(codeInvalidFilter, "Invalid changedSince [yesterday], expected an RFC 3339 time such as 2017-01-31T15:04:05Z")},
+		{"Service unavailable - get organisations", newRequest("GET", "/transformers/organisations"), &dummyService{found: false, initialised: false, orgs: []org{}}, http.StatusServiceUnavailable, "application/json", notLoaded},
+		{"Internal error - get organisations", newRequest("GET", "/transformers/organisations"), &dummyService{initialised: true, err: errors.New("cache file corrupt")}, http.StatusInternalServerError, "application/json", errorBody(codeInternal, "Error reading the organisations")},
+		{"Success - get count", newRequest("GET", "/transformers/organisations/__count"), &dummyService{found: true, initialised: true, orgs: []org{org{UUID: testUUID}}}, http.StatusOK, "text/plain; charset=utf-8", "1"},
+		{"Service unavailable - get count", newRequest("GET", "/transformers/organisations/__count"), &dummyService{initialised: false}, http.StatusServiceUnavailable, "application/json", notLoaded},
+		{"Internal error - get count", newRequest("GET", "/transformers/organisations/__count"), &dummyService{initialised: true, err: errors.New("cache file corrupt")}, http.StatusInternalServerError, "application/json", errorBody(codeInternal, "Error counting the organisations")},
 		{"Success - get IDs", newRequest("GET", "/transformers/organisations/__ids"), &dummyService{found: true, initialised: true, orgs: []org{org{UUID: testUUID}}}, http.StatusOK, "application/json", testIDs},
+		{"Service unavailable - get IDs", newRequest("GET", "/transformers/organisations/__ids"), &dummyService{initialised: false}, http.StatusServiceUnavailable, "application/json", notLoaded},
+		{"Internal error - get IDs", newRequest("GET", "/transformers/organisations/__ids"), &dummyService{initialised: true, err: errors.New("cache file corrupt")}, http.StatusInternalServerError, "application/json", errorBody(codeInternal, "Error reading the organisation IDs")},
+		{"Accepted - reload", newRequest("POST", "/transformers/organisations/__reload"), &dummyService{initialised: true}, http.StatusAccepted, "application/json", "{\"id\":\"" + testLoadID + "\",\"message\":\"Reloading V1 organisations\"}\n"},
+		{"Accepted - resume reload", newRequest("POST", "/transformers/organisations/__reload?resume=true"), &dummyService{initialised: true, resumeFrom: 20000, resumable: true}, http.StatusAccepted, "application/json", "{\"id\":\"" + testLoadID + "\",\"message\":\"Resuming V1 organisations load from offset 20000\"}\n"},
+		{"Conflict - nothing to resume", newRequest("POST", "/transformers/organisations/__reload?resume=true"), &dummyService{initialised: true}, http.StatusConflict, "application/json", errorBody(codeNothingToResume, "No partial load to resume")},
+		{"Conflict - load running", newRequest("POST", "/transformers/organisations/__reload"), &dummyService{initialised: true, running: testLoadID}, http.StatusConflict, "application/json", errorBody(codeLoadRunning, "A load is already running: "+testLoadID)},
+		{"Accepted - cancel reload", newRequest("DELETE", "/transformers/organisations/__reload/"+testLoadID), &dummyService{initialised: true, running: testLoadID}, http.StatusAccepted, "application/json", "{\"id\":\"" + testLoadID + "\",\"message\":\"Cancelling load " + testLoadID + "\"}\n"},
+		{"Not found - cancel finished reload", newRequest("DELETE", "/transformers/organisations/__reload/"+testLoadID), &dummyService{initialised: true}, http.StatusNotFound, "application/json", errorBody(codeLoadNotRunning, "No running load "+testLoadID)},
+		{"Not found - cancel reload with quotes in its ID", newRequest("DELETE", "/transformers/organisations/__reload/%22quoted%22"), &dummyService{initialised: true}, http.StatusNotFound, "application/json", errorBody(codeLoadNotRunning, "No running load \"quoted\"")},
+		{"Success - get conflicts", newRequest("GET", "/transformers/organisations/__conflicts"), &dummyService{initialised: true, conflicts: []uuidConflict{uuidConflict{UUID: testUUID, Orgs: []org{org{UUID: testUUID}, org{UUID: testUUID}}}}}, http.StatusOK, "application/json", testConflicts},
+		{"Service unavailable - get conflicts", newRequest("GET", "/transformers/organisations/__conflicts"), &dummyService{initialised: false}, http.StatusServiceUnavailable, "application/json", notLoaded},
+		{"Success - get validation", newRequest("GET", "/transformers/organisations/__validation"), &dummyService{initialised: true, validation: validationSummary{Checked: 1, Rules: []ruleResult{ruleResult{Rule: "empty-name", Count: 1, SampleUUIDs: []string{testUUID}}}}}, http.StatusOK, "application/json", testValidation},
+		{"Service unavailable - get validation", newRequest("GET", "/transformers/organisations/__validation"), &dummyService{initialised: false}, http.StatusServiceUnavailable, "application/json", notLoaded},
 	}
+}
 
-	for _, test := range tests {
+func TestHandlers(t *testing.T) {
+	assert := assert.New(t)
+	for _, test := range handlerTests() {
 		rec := httptest.NewRecorder()
 		router(test.dummyService).ServeHTTP(rec, test.req)
 		assert.True(test.statusCode == rec.Code, fmt.Sprintf("%s: Wrong response code, was %d, should be %d", test.name, rec.Code, test.statusCode))
 		assert.Equal(test.body, rec.Body.String(), fmt.Sprintf("%s: Wrong body", test.name))
+		assert.Equal(test.contentType, rec.Header().Get("Content-Type"), fmt.Sprintf("%s: Wrong content type", test.name))
 	}
 }
 
+func TestGetOrgsLinksTheNextPage(t *testing.T) {
+	assert := assert.New(t)
+	service := &dummyService{initialised: true, orgs: []org{org{UUID: "a"}, org{UUID: "b"}, org{UUID: "c"}}}
+	tests := []struct {
+		name string
+		url  string
+		link string
+	}{
+		{"More orgs follow", "/transformers/organisations?limit=2", `</transformers/organisations?after=b&limit=2>; rel="next"`},
+		{"Last page", "/transformers/organisations?limit=2&after=b", ""},
+		{"No limit", "/transformers/organisations", ""},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		router(service).ServeHTTP(rec, newRequest("GET", test.url))
+		assert.Equal(http.StatusOK, rec.Code, test.name)
+		assert.Equal(test.link, rec.Header().Get("Link"), test.name)
+	}
+}
+
+// errorBody is the error response of the handlers to requests made by newRequest
+func errorBody(code string, msg string) string {
+	body, _ := json.Marshal(errorResponse{Code: code, Message: msg, TransactionID: testTransactionID})
+	return string(body) + "\n"
+}
+
 func newRequest(method, url string) *http.Request {
 	req, err := http.NewRequest(method, url, nil)
 	if err != nil {
 		panic(err)
 	}
+	req.Header.Set("X-Request-Id", testTransactionID)
 	return req
 }
 
@@ -59,6 +128,9 @@ func router(s orgsService) *mux.Router {
 	m.HandleFunc("/transformers/organisations/__count", h.getOrgCount).Methods("GET")
 	m.HandleFunc("/transformers/organisations/__ids", h.getOrgIds).Methods("GET")
 	m.HandleFunc("/transformers/organisations/__reload", h.reloadOrgs).Methods("POST")
+	m.HandleFunc("/transformers/organisations/__reload/{id}", h.cancelReload).Methods("DELETE")
+	m.HandleFunc("/transformers/organisations/__conflicts", h.getConflicts).Methods("GET")
+	m.HandleFunc("/transformers/organisations/__validation", h.getValidation).Methods("GET")
 	m.HandleFunc("/transformers/organisations", h.getOrgs).Methods("GET")
 	m.HandleFunc("/transformers/organisations/{uuid}", h.getOrgByUUID).Methods("GET")
 	return m
@@ -68,17 +140,37 @@ type dummyService struct {
 	found       bool
 	orgs        []org
 	initialised bool
+	conflicts   []uuidConflict
+	validation  validationSummary
+	lastLoad    *loadStatus
+	resumeFrom  int
+	resumable   bool
+	stopped     bool
+	hangs       bool // shutdown only returns once its context is done
+	running     string
+	err         error
 }
 
-func (s *dummyService) getOrgs() ([]orgLink, error) {
+func (s *dummyService) getOrgs(ctx context.Context, filter orgFilter, page orgPage) ([]orgLink, string, error) {
 	var orgLinks []orgLink
+	var last string
 	for _, sub := range s.orgs {
+		if sub.UUID <= page.after || !filter.matches(sub, time.Time{}) {
+			continue
+		}
+		if page.full(len(orgLinks)) {
+			return orgLinks, last, s.err
+		}
 		orgLinks = append(orgLinks, orgLink{APIURL: "http://localhost:8080/transformers/organisations/" + sub.UUID})
+		last = sub.UUID
 	}
-	return orgLinks, nil
+	return orgLinks, "", s.err
 }
 
-func (s *dummyService) getOrgByUUID(uuid string) (org, bool, error) {
+func (s *dummyService) getOrgByUUID(ctx context.Context, uuid string) (org, bool, error) {
+	if s.err != nil {
+		return org{}, false, s.err
+	}
 	return s.orgs[0], s.found, nil
 }
 
@@ -86,26 +178,86 @@ func (s *dummyService) isInitialised() bool {
 	return s.initialised
 }
 
-func (s *dummyService) shutdown() error {
+func (s *dummyService) shutdown(ctx context.Context) error {
+	s.stopped = true
+	if s.hangs {
+		<-ctx.Done()
+		return ctx.Err()
+	}
 	return nil
 }
 
-func (s *dummyService) orgCount() (int, error) {
-	return len(s.orgs), nil
+func (s *dummyService) orgCount(ctx context.Context) (int, error) {
+	return len(s.orgs), s.err
 }
 
-func (s *dummyService) orgIds() ([]orgUUID, error) {
+func (s *dummyService) orgIds(ctx context.Context) ([]orgUUID, error) {
 	var orgUUIDs []orgUUID
 	for _, sub := range s.orgs {
 		orgUUIDs = append(orgUUIDs, orgUUID{UUID: sub.UUID})
 	}
-	return orgUUIDs, nil
+	return orgUUIDs, s.err
 }
 
-func (s *dummyService) orgReload() error {
-	return nil
+func (s *dummyService) orgReload(ctx context.Context, resume bool) (string, error) {
+	if resume && !s.resumable {
+		return "", errNothingToResume
+	}
+	if s.running != "" {
+		return "", fmt.Errorf("%w: %v", errLoadRunning, s.running)
+	}
+	return testLoadID, nil
+}
+
+func (s *dummyService) cancelLoad(id string) bool {
+	return s.running != "" && s.running == id
+}
+
+func (s *dummyService) resumeOffset() (int, bool) {
+	return s.resumeFrom, s.resumable
 }
 
 func (s *dummyService) isDataLoaded() bool {
-	return true
+	return s.initialised
+}
+
+func (s *dummyService) uuidConflicts() []uuidConflict {
+	return s.conflicts
+}
+
+func (s *dummyService) validationSummary() validationSummary {
+	return s.validation
+}
+
+func (s *dummyService) lastLoadStatus() (loadStatus, bool) {
+	if s.lastLoad == nil {
+		return loadStatus{}, false
+	}
+	return *s.lastLoad, true
+}
+
+func TestLoadStatusHealthAndGTG(t *testing.T) {
+	assert := assert.New(t)
+	failed := &loadStatus{Outcome: loadFailed, Error: "TME unavailable", FailedOffsets: []int{0}}
+	tests := []struct {
+		name         string
+		dummyService *dummyService
+		healthy      bool
+		goodToGo     bool
+		message      string
+	}{
+		{"No load finished", &dummyService{initialised: false}, true, false, "Organisations are not loaded yet"},
+		{"Load succeeded", &dummyService{initialised: true, lastLoad: &loadStatus{Outcome: loadSucceeded}}, true, true, ""},
+		{"First load failed", &dummyService{initialised: false, lastLoad: failed}, false, false, failed.String()},
+		{"Reload failed keeping previous data", &dummyService{initialised: true, lastLoad: failed}, false, true, ""},
+	}
+
+	for _, test := range tests {
+		h := newOrgsHandler(test.dummyService)
+		_, err := h.LoadStatusCheck().Checker()
+		assert.Equal(test.healthy, err == nil, fmt.Sprintf("%s: Wrong health", test.name))
+		status := h.GTG()
+		assert.Equal(test.goodToGo, status.GoodToGo, fmt.Sprintf("%s: Wrong gtg", test.name))
+		assert.Equal(test.message, status.Message, fmt.Sprintf("%s: Wrong gtg message", test.name))
+	}
 }
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pborman/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	loadSucceeded = "success"
+	loadPartial   = "partial"
+	loadFailed    = "failed"
+	loadCancelled = "cancelled"
+)
+
+// loadStatus is the outcome of a load from TME
+type loadStatus struct {
+	ID            string    `json:"id"`
+	Outcome       string    `json:"outcome"`
+	Error         string    `json:"error,omitempty"`
+	FailedOffsets []int     `json:"failedOffsets,omitempty"`
+	Started       time.Time `json:"started"`
+	Finished      time.Time `json:"finished"`
+}
+
+func (l loadStatus) String() string {
+	msg := fmt.Sprintf("Load started at %v finished with outcome [%v]", l.Started.Format(time.RFC3339), l.Outcome)
+	if l.Error != "" {
+		msg += ": " + l.Error
+	}
+	if len(l.FailedOffsets) > 0 {
+		msg += fmt.Sprintf(" (failed page offsets %v)", l.FailedOffsets)
+	}
+	return msg
+}
+
+// loadTracker records the pages that failed during a running load. Its logger tags every line with the load ID.
+type loadTracker struct {
+	sync.Mutex
+	id            string
+	log           *log.Entry
+	started       time.Time
+	failedOffsets map[int]error
+}
+
+func newLoadTracker() *loadTracker {
+	id := uuid.New()
+	return &loadTracker{id: id, log: log.WithField("loadID", id), started: time.Now(), failedOffsets: make(map[int]error)}
+}
+
+func (l *loadTracker) pageFailed(offset int, err error) {
+	l.Lock()
+	defer l.Unlock()
+	l.failedOffsets[offset] = err
+}
+
+// ignoreFailuresAfter forgets the pages that failed past the given offset, all of them being empty
+func (l *loadTracker) ignoreFailuresAfter(offset int) {
+	l.Lock()
+	defer l.Unlock()
+	if offset < 0 {
+		return
+	}
+	for failed := range l.failedOffsets {
+		if failed > offset {
+			delete(l.failedOffsets, failed)
+		}
+	}
+}
+
+func (l *loadTracker) failures() []int {
+	l.Lock()
+	defer l.Unlock()
+	offsets := make([]int, 0, len(l.failedOffsets))
+	for offset := range l.failedOffsets {
+		offsets = append(offsets, offset)
+	}
+	sort.Ints(offsets)
+	return offsets
+}
+
+// finish works out the outcome: a load with failed pages is partial unless it was cancelled or aborted before reaching the last page
+func (l *loadTracker) finish(err error, complete bool) loadStatus {
+	status := loadStatus{ID: l.id, Outcome: loadSucceeded, FailedOffsets: l.failures(), Started: l.started, Finished: time.Now()}
+	if err != nil {
+		status.Error = err.Error()
+		status.Outcome = loadFailed
+		if errors.Is(err, context.Canceled) {
+			status.Outcome = loadCancelled
+		} else if complete && len(status.FailedOffsets) > 0 {
+			status.Outcome = loadPartial
+		}
+	}
+	return status
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var logFormatters = map[string]log.Formatter{
+	"json": &log.JSONFormatter{},
+	"text": &log.TextFormatter{FullTimestamp: true},
+}
+
+// configureLogging sets the level and format of the standard logger
+func configureLogging(level string, format string) error {
+	lvl, err := log.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	formatter, ok := logFormatters[format]
+	if !ok {
+		return fmt.Errorf("Unknown log format [%v], expected json or text", format)
+	}
+	log.SetLevel(lvl)
+	log.SetFormatter(formatter)
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigureLogging(t *testing.T) {
+	assert := assert.New(t)
+	defer configureLogging("info", "text")
+
+	tests := []struct {
+		name   string
+		level  string
+		format string
+		valid  bool
+	}{
+		{"JSON debug", "debug", "json", true},
+		{"Text warnings", "warning", "text", true},
+		{"Unknown level", "chatty", "json", false},
+		{"Unknown format", "info", "xml", false},
+	}
+
+	for _, test := range tests {
+		err := configureLogging(test.level, test.format)
+		assert.Equal(test.valid, err == nil, fmt.Sprintf("%s: Unexpected result %v", test.name, err))
+		if test.valid {
+			assert.Equal(test.level, log.GetLevel().String(), fmt.Sprintf("%s: Wrong level", test.name))
+		}
+	}
+}
+
+func TestLoadLogFields(t *testing.T) {
+	assert := assert.New(t)
+	hook := logtest.NewGlobal()
+	defer log.StandardLogger().ReplaceHooks(make(log.LevelHooks))
+
+	s := newPipelineTestService(&pagedRepo{total: 25, pageSize: 10}, testCacheFile(t), pipelineConfig{})
+	assert.NoError(s.init())
+	defer s.shutdown(context.Background())
+	status, _ := s.lastLoadStatus()
+
+	stored := map[interface{}]interface{}{}
+	var loaded interface{}
+	for _, e := range hook.AllEntries() {
+		if e.Data["loadID"] == nil {
+			continue
+		}
+		assert.Equal(status.ID, e.Data["loadID"], fmt.Sprintf("%s: Entry tagged with another load", e.Message))
+		switch e.Message {
+		case "Stored page":
+			stored[e.Data["offset"]] = e.Data["count"]
+		case "Loaded organisations":
+			loaded = e.Data["count"]
+		}
+	}
+	assert.Equal(map[interface{}]interface{}{0: 10, 10: 10, 20: 5}, stored, "Each stored page should be logged with its offset and count")
+	assert.Equal(25, loaded, "Load should be logged with the number of orgs loaded")
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// orgLRU is a bounded, least-recently-used cache of decoded orgs kept in front of bolt
+type orgLRU struct {
+	sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+	hits     metrics.Counter
+	misses   metrics.Counter
+}
+
+type lruEntry struct {
+	uuid string
+	org  org
+}
+
+func newOrgLRU(capacity int) *orgLRU {
+	return &orgLRU{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		hits:     metrics.GetOrRegisterCounter("org_cache.hits", metrics.DefaultRegistry),
+		misses:   metrics.GetOrRegisterCounter("org_cache.misses", metrics.DefaultRegistry),
+	}
+}
+
+func (c *orgLRU) enabled() bool {
+	return c != nil && c.capacity > 0
+}
+
+func (c *orgLRU) get(uuid string) (org, bool) {
+	if !c.enabled() {
+		return org{}, false
+	}
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.items[uuid]; ok {
+		c.ll.MoveToFront(e)
+		c.hits.Inc(1)
+		return e.Value.(*lruEntry).org, true
+	}
+	c.misses.Inc(1)
+	return org{}, false
+}
+
+func (c *orgLRU) add(uuid string, o org) {
+	if !c.enabled() {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.items[uuid]; ok {
+		c.ll.MoveToFront(e)
+		e.Value.(*lruEntry).org = o
+		return
+	}
+	c.items[uuid] = c.ll.PushFront(&lruEntry{uuid: uuid, org: o})
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).uuid)
+	}
+}
+
+func (c *orgLRU) purge() {
+	if !c.enabled() {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+func (c *orgLRU) len() int {
+	if !c.enabled() {
+		return 0
+	}
+	c.Lock()
+	defer c.Unlock()
+	return c.ll.Len()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrgLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	assert := assert.New(t)
+	cache := newOrgLRU(2)
+	cache.add("a", org{UUID: "a"})
+	cache.add("b", org{UUID: "b"})
+
+	_, found := cache.get("a")
+	assert.True(found, "a should be cached")
+
+	cache.add("c", org{UUID: "c"})
+	_, found = cache.get("b")
+	assert.False(found, "b should have been evicted as least recently used")
+	o, found := cache.get("a")
+	assert.True(found, "a should still be cached")
+	assert.Equal("a", o.UUID)
+	assert.Equal(2, cache.len())
+}
+
+func TestOrgLRUPurge(t *testing.T) {
+	assert := assert.New(t)
+	cache := newOrgLRU(10)
+	cache.add("a", org{UUID: "a"})
+	cache.purge()
+
+	_, found := cache.get("a")
+	assert.False(found, "cache should be empty after purge")
+	assert.Equal(0, cache.len())
+}
+
+func TestOrgLRUMetrics(t *testing.T) {
+	assert := assert.New(t)
+	cache := newOrgLRU(10)
+	hits, misses := cache.hits.Count(), cache.misses.Count()
+
+	cache.get("a")
+	cache.add("a", org{UUID: "a"})
+	cache.get("a")
+
+	assert.Equal(hits+1, cache.hits.Count(), "hit count incorrect")
+	assert.Equal(misses+1, cache.misses.Count(), "miss count incorrect")
+}
+
+func TestOrgLRUDisabled(t *testing.T) {
+	assert := assert.New(t)
+	cache := newOrgLRU(0)
+	cache.add("a", org{UUID: "a"})
+
+	_, found := cache.get("a")
+	assert.False(found, "disabled cache should never hit")
+	assert.Equal(0, cache.len())
+}
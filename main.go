@@ -1,14 +1,18 @@
 package main
 
 import (
-	"crypto/tls"
+	"context"
 	"fmt"
 	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
+	api "github.com/Financial-Times/api-endpoint"
 	fthealth "github.com/Financial-Times/go-fthealth/v1_1"
 	"github.com/Financial-Times/http-handlers-go/httphandlers"
 	"github.com/Financial-Times/service-status-go/gtg"
@@ -16,8 +20,8 @@ import (
 	"github.com/Financial-Times/tme-reader/tmereader"
 	"github.com/gorilla/mux"
 	"github.com/jawher/mow.cli"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rcrowley/go-metrics"
-	"github.com/sethgrid/pester"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -53,6 +57,54 @@ func main() {
 		Desc:   "TME base url",
 		EnvVar: "TME_BASE_URL",
 	})
+	tmeTLSInsecure := app.Bool(cli.BoolOpt{
+		Name:   "tme-tls-insecure",
+		Value:  false,
+		Desc:   "Skip verification of the TME server certificate",
+		EnvVar: "TME_TLS_INSECURE",
+	})
+	tmeCABundle := app.String(cli.StringOpt{
+		Name:   "tme-ca-bundle",
+		Value:  "",
+		Desc:   "Path to a PEM bundle of CA certificates trusted for TME on top of the system ones",
+		EnvVar: "TME_CA_BUNDLE",
+	})
+	tmeClientCert := app.String(cli.StringOpt{
+		Name:   "tme-client-cert",
+		Value:  "",
+		Desc:   "Path to a PEM client certificate presented to TME",
+		EnvVar: "TME_CLIENT_CERT",
+	})
+	tmeClientKey := app.String(cli.StringOpt{
+		Name:   "tme-client-key",
+		Value:  "",
+		Desc:   "Path to the PEM key of the TME client certificate",
+		EnvVar: "TME_CLIENT_KEY",
+	})
+	tmeRetries := app.Int(cli.IntOpt{
+		Name:   "tme-retries",
+		Value:  5,
+		Desc:   "Number of attempts made for each request to TME",
+		EnvVar: "TME_RETRIES",
+	})
+	tmeTimeout := app.Int(cli.IntOpt{
+		Name:   "tme-timeout",
+		Value:  30,
+		Desc:   "Seconds allowed to connect to TME and to complete each request",
+		EnvVar: "TME_TIMEOUT",
+	})
+	tmeBackoff := app.String(cli.StringOpt{
+		Name:   "tme-backoff",
+		Value:  "exponential",
+		Desc:   fmt.Sprintf("Backoff between attempts of a TME request, one of %v", backoffStrategyNames()),
+		EnvVar: "TME_BACKOFF",
+	})
+	tmeConcurrency := app.Int(cli.IntOpt{
+		Name:   "tme-concurrency",
+		Value:  1,
+		Desc:   "Number of parallel attempts made for each request to TME",
+		EnvVar: "TME_CONCURRENCY",
+	})
 	port := app.Int(cli.IntOpt{
 		Name:   "port",
 		Value:  8080,
@@ -77,14 +129,215 @@ func main() {
 		Desc:   "Cache file name",
 		EnvVar: "CACHE_FILE_NAME",
 	})
+	lruCacheSize := app.Int(cli.IntOpt{
+		Name:   "lru-cache-size",
+		Value:  1000,
+		Desc:   "Number of decoded organisations kept in memory in front of the cache file (0 disables it)",
+		EnvVar: "LRU_CACHE_SIZE",
+	})
+	enabledValidationRules := app.Strings(cli.StringsOpt{
+		Name:   "validation-rules",
+		Value:  validationRuleNames(),
+		Desc:   "Data-quality rules run over every transformed organisation during a load",
+		EnvVar: "VALIDATION_RULES",
+	})
+	maxCountDrop := app.Int(cli.IntOpt{
+		Name:   "max-count-drop",
+		Value:  0,
+		Desc:   "Abort a load returning more than this many organisations fewer than the previous one (0 disables it)",
+		EnvVar: "MAX_COUNT_DROP",
+	})
+	maxCountDropPercent := app.Int(cli.IntOpt{
+		Name:   "max-count-drop-percent",
+		Value:  10,
+		Desc:   "Abort a load returning more than this percentage of organisations fewer than the previous one (0 disables it)",
+		EnvVar: "MAX_COUNT_DROP_PERCENT",
+	})
+	pageRetries := app.Int(cli.IntOpt{
+		Name:   "page-retries",
+		Value:  3,
+		Desc:   "Number of times a failed TME page is fetched again before being recorded as failed",
+		EnvVar: "PAGE_RETRIES",
+	})
+	pageRetryBackoff := app.Int(cli.IntOpt{
+		Name:   "page-retry-backoff",
+		Value:  2,
+		Desc:   "Seconds to wait before the first page retry, doubled on each further retry",
+		EnvVar: "PAGE_RETRY_BACKOFF",
+	})
+	maxPageFailures := app.Int(cli.IntOpt{
+		Name:   "max-page-failures",
+		Value:  3,
+		Desc:   "Number of pages in a row that may fail before the load is aborted",
+		EnvVar: "MAX_PAGE_FAILURES",
+	})
+	adminAPIKeys := app.Strings(cli.StringsOpt{
+		Name:   "admin-api-keys",
+		Value:  []string{},
+		Desc:   "name:key pairs allowed to call the admin endpoints, which are unavailable when no key is given",
+		EnvVar: "ADMIN_API_KEYS",
+	})
+	adminAPIKeysFile := app.String(cli.StringOpt{
+		Name:   "admin-api-keys-file",
+		Value:  "",
+		Desc:   "Path to a file of name:key pairs, one per line, allowed to call the admin endpoints",
+		EnvVar: "ADMIN_API_KEYS_FILE",
+	})
+	adminAuthDisabled := app.Bool(cli.BoolOpt{
+		Name:   "admin-auth-disabled",
+		Value:  false,
+		Desc:   "Let anyone call the admin endpoints, without keys",
+		EnvVar: "ADMIN_AUTH_DISABLED",
+	})
+	rateLimits := app.Strings(cli.StringsOpt{
+		Name:   "rate-limits",
+		Value:  []string{},
+		Desc:   "Per-client limits as route=rate/burst, the route being a path template and rate in requests a second, none by default",
+		EnvVar: "RATE_LIMITS",
+	})
+	trustedProxies := app.Strings(cli.StringsOpt{
+		Name:   "trusted-proxies",
+		Value:  []string{},
+		Desc:   "Addresses or CIDR ranges of the proxies whose X-Forwarded-For identifies rate-limited clients",
+		EnvVar: "TRUSTED_PROXIES",
+	})
+	apiYml := app.String(cli.StringOpt{
+		Name:   "api-yml",
+		Value:  "./_ft/api.yml",
+		Desc:   "Location of the OpenAPI specification served at " + api.DefaultPath,
+		EnvVar: "API_YML",
+	})
+	shutdownTimeout := app.Int(cli.IntOpt{
+		Name:   "shutdown-timeout",
+		Value:  20,
+		Desc:   "Seconds allowed on SIGTERM to drain in-flight requests, stop any running load and close the cache file",
+		EnvVar: "SHUTDOWN_TIMEOUT",
+	})
+	logLevel := app.String(cli.StringOpt{
+		Name:   "log-level",
+		Value:  "info",
+		Desc:   "Logging level: debug, info, warn or error",
+		EnvVar: "LOG_LEVEL",
+	})
+	logFormat := app.String(cli.StringOpt{
+		Name:   "log-format",
+		Value:  "json",
+		Desc:   "Logging format: json or text",
+		EnvVar: "LOG_FORMAT",
+	})
+	otlpEndpoint := app.String(cli.StringOpt{
+		Name:   "otlp-endpoint",
+		Value:  "",
+		Desc:   "host:port of the OTLP/gRPC collector traces are exported to (tracing is disabled when empty)",
+		EnvVar: "OTLP_ENDPOINT",
+	})
+	otlpInsecure := app.Bool(cli.BoolOpt{
+		Name:   "otlp-insecure",
+		Value:  false,
+		Desc:   "Export traces to the OTLP collector without TLS",
+		EnvVar: "OTLP_INSECURE",
+	})
+	traceSampleRatio := app.Float64(cli.Float64Opt{
+		Name:   "trace-sample-ratio",
+		Value:  1,
+		Desc:   "Fraction of traces started by this service that are sampled, incoming sampling decisions are kept",
+		EnvVar: "TRACE_SAMPLE_RATIO",
+	})
+	loadFetchers := app.Int(cli.IntOpt{
+		Name:   "load-fetchers",
+		Value:  1,
+		Desc:   "Number of TME pages fetched in parallel during a load",
+		EnvVar: "LOAD_FETCHERS",
+	})
+	loadTransformers := app.Int(cli.IntOpt{
+		Name:   "load-transformers",
+		Value:  1,
+		Desc:   "Number of pages transformed in parallel during a load",
+		EnvVar: "LOAD_TRANSFORMERS",
+	})
+	loadWriters := app.Int(cli.IntOpt{
+		Name:   "load-writers",
+		Value:  2,
+		Desc:   "Number of pages written to the cache file in parallel during a load",
+		EnvVar: "LOAD_WRITERS",
+	})
+
+	source := app.String(cli.StringOpt{
+		Name:   "source",
+		Value:  "tme",
+		Desc:   "Where organisations are loaded from: tme, or file:/path for taxonomy XML files (comma-separated files, directories or glob patterns)",
+		EnvVar: "SOURCE",
+	})
 
 	tmeTaxonomyName := "ON"
 
+	app.Before = func() {
+		if err := configureLogging(*logLevel, *logFormat); err != nil {
+			log.Fatalf("Invalid logging configuration: %v", err)
+		}
+	}
+
+	app.Command("transform", "Transform TME taxonomy XML into NDJSON organisations on stdout", transformCommand)
+	app.Command("cache", "Inspect an existing cache file, opened read-only", cacheCommand(cacheFileName))
+	app.Command("diff", "Compare the organisations of two cache files", diffCommand)
+
 	app.Action = func() {
-		client := getResilientClient()
+		validation, err := newValidationReport(*enabledValidationRules)
+		if err != nil {
+			log.Fatalf("Invalid validation rules: %v", err)
+		}
+		shutdownTracing, err := setupTracing(*otlpEndpoint, *otlpInsecure, *traceSampleRatio)
+		if err != nil {
+			log.Fatalf("Invalid tracing configuration: %v", err)
+		}
+		defer shutdownTracing(context.Background())
+		auth, err := newAdminAuth(*adminAPIKeys, *adminAPIKeysFile, *adminAuthDisabled)
+		if err != nil {
+			log.Fatalf("Invalid admin keys: %v", err)
+		}
+		switch {
+		case *adminAuthDisabled:
+			log.Warn("Admin authentication is disabled, admin endpoints are open to anyone")
+		case !auth.enabled():
+			log.Warn("No admin keys configured, admin endpoints are unavailable")
+		}
+		limits, err := parseRateLimits(*rateLimits)
+		if err != nil {
+			log.Fatalf("Invalid rate limits: %v", err)
+		}
+		proxies, err := parseTrustedProxies(*trustedProxies)
+		if err != nil {
+			log.Fatalf("Invalid trusted proxies: %v", err)
+		}
 		modelTransformer := new(orgTransformer)
-		s := newOrgService(
-			tmereader.NewTmeRepository(
+		var repository tmereader.Repository
+		switch {
+		case strings.HasPrefix(*source, fileSourcePrefix):
+			repository, err = newFileRepository(strings.TrimPrefix(*source, fileSourcePrefix), *maxRecords, modelTransformer)
+			if err != nil {
+				log.Fatalf("Invalid file source: %v", err)
+			}
+			log.Infof("Loading organisations from %v", *source)
+		case *source == "tme":
+			tlsConfig, err := newTLSConfig(*tmeTLSInsecure, *tmeCABundle, *tmeClientCert, *tmeClientKey)
+			if err != nil {
+				log.Fatalf("Invalid TME TLS configuration: %v", err)
+			}
+			if *tmeTLSInsecure {
+				log.Warn("TME server certificate verification is disabled")
+			}
+			clientCfg := clientConfig{
+				retries:     *tmeRetries,
+				timeout:     time.Duration(*tmeTimeout) * time.Second,
+				backoff:     *tmeBackoff,
+				concurrency: *tmeConcurrency,
+			}
+			client, err := getResilientClient(tlsConfig, clientCfg)
+			if err != nil {
+				log.Fatalf("Invalid TME client configuration: %v", err)
+			}
+			log.Infof("TME client configuration: %v tlsInsecure=%v caBundle=%q clientCert=%q", clientCfg, *tmeTLSInsecure, *tmeCABundle, *tmeClientCert)
+			repository = tmereader.NewTmeRepository(
 				client,
 				*tmeBaseURL,
 				*username,
@@ -94,49 +347,32 @@ func main() {
 				*batchSize,
 				tmeTaxonomyName,
 				&tmereader.AuthorityFiles{},
-				modelTransformer),
+				modelTransformer)
+		default:
+			log.Fatalf("Unknown source [%v], expected tme or %v/path", *source, fileSourcePrefix)
+		}
+		s := newOrgService(
+			instrumentedRepository{repository},
 			*baseURL,
 			tmeTaxonomyName,
 			*maxRecords,
-			*cacheFileName)
-		defer s.shutdown()
-		handler := newOrgsHandler(s)
-		servicesRouter := mux.NewRouter()
-		servicesRouter.HandleFunc(status.PingPath, status.PingHandler)
-		servicesRouter.HandleFunc(status.PingPathDW, status.PingHandler)
-		servicesRouter.HandleFunc(status.BuildInfoPath, status.BuildInfoHandler)
-		servicesRouter.HandleFunc(status.BuildInfoPathDW, status.BuildInfoHandler)
+			*cacheFileName,
+			*lruCacheSize,
+			validation,
+			countDropGuard{maxDrop: *maxCountDrop, maxDropPercent: *maxCountDropPercent},
+			pageRetryPolicy{retries: *pageRetries, backoff: time.Duration(*pageRetryBackoff) * time.Second, maxConsecutiveFailures: *maxPageFailures},
+			pipelineConfig{fetchers: *loadFetchers, transformers: *loadTransformers, writers: *loadWriters})
+		registerServiceMetrics(s, *cacheFileName)
+		http.Handle("/", serviceHandler(s, auth, newRateLimiter(limits, proxies), *apiYml))
 
-		healthCheck := fthealth.TimedHealthCheck{
-			HealthCheck: fthealth.HealthCheck{
-				SystemCode:  "v1-orgs-transformer",
-				Name:        "V1 Org Transformer Healthchecks",
-				Description: "Checks for the health of the service",
-				Checks: []fthealth.Check{
-					handler.HealthCheck(),
-				},
-			},
-			Timeout: 10 * time.Second,
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+		if err != nil {
+			log.Fatalf("Error listening on port %d: %v", *port, err)
 		}
-
-		servicesRouter.HandleFunc("/__health", fthealth.Handler(healthCheck))
-		g2gHandler := status.NewGoodToGoHandler(gtg.StatusChecker(handler.GTG))
-		servicesRouter.HandleFunc(status.GTGPath, g2gHandler)
-
-		servicesRouter.HandleFunc("/transformers/organisations/__count", handler.getOrgCount).Methods("GET")
-		servicesRouter.HandleFunc("/transformers/organisations/__ids", handler.getOrgIds).Methods("GET")
-		servicesRouter.HandleFunc("/transformers/organisations/__reload", handler.reloadOrgs).Methods("POST")
-
-		servicesRouter.HandleFunc("/transformers/organisations/{uuid:[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}}", handler.getOrgByUUID).Methods("GET")
-		servicesRouter.HandleFunc("/transformers/organisations", handler.getOrgs).Methods("GET")
-
-		var h http.Handler = servicesRouter
-		h = httphandlers.TransactionAwareRequestLoggingHandler(log.StandardLogger(), h)
-		h = httphandlers.HTTPMetricsHandler(metrics.DefaultRegistry, h)
-		http.Handle("/", h)
-
 		log.Printf("listening on %d", *port)
-		err := http.ListenAndServe(fmt.Sprintf(":%d", *port), nil)
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+		err = serve(&http.Server{}, listener, s, time.Duration(*shutdownTimeout)*time.Second, stop)
 		if err != nil {
 			log.Errorf("Error by listen and serve: %v", err.Error())
 		}
@@ -144,23 +380,54 @@ func main() {
 	app.Run(os.Args)
 }
 
-func getResilientClient() *pester.Client {
-	tr := &http.Transport{
-		MaxIdleConnsPerHost: 32,
-		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
-		Dial: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).Dial,
+// serviceHandler routes the endpoints of the service through the middleware they are served with
+func serviceHandler(s orgsService, auth *adminAuth, limiter *rateLimiter, apiYml string) http.Handler {
+	handler := newOrgsHandler(s)
+	servicesRouter := mux.NewRouter()
+	servicesRouter.NotFoundHandler = http.HandlerFunc(notFound)
+	servicesRouter.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowed)
+	servicesRouter.HandleFunc(status.PingPath, status.PingHandler)
+	servicesRouter.HandleFunc(status.PingPathDW, status.PingHandler)
+	servicesRouter.HandleFunc(status.BuildInfoPath, status.BuildInfoHandler)
+	servicesRouter.HandleFunc(status.BuildInfoPathDW, status.BuildInfoHandler)
+	servicesRouter.Handle("/metrics", promhttp.Handler())
+	if apiEndpoint, err := api.NewAPIEndpointForFile(apiYml); err != nil {
+		log.WithError(err).Warnf("Not serving the API specification at %v", api.DefaultPath)
+	} else {
+		servicesRouter.HandleFunc(api.DefaultPath, apiEndpoint.ServeHTTP).Methods("GET")
 	}
-	c := &http.Client{
-		Transport: tr,
-		Timeout:   30 * time.Second,
+
+	healthCheck := fthealth.TimedHealthCheck{
+		HealthCheck: fthealth.HealthCheck{
+			SystemCode:  "v1-orgs-transformer",
+			Name:        "V1 Org Transformer Healthchecks",
+			Description: "Checks for the health of the service",
+			Checks: []fthealth.Check{
+				handler.HealthCheck(),
+				handler.LoadStatusCheck(),
+			},
+		},
+		Timeout: 10 * time.Second,
 	}
-	client := pester.NewExtendedClient(c)
-	client.Backoff = pester.ExponentialBackoff
-	client.MaxRetries = 5
-	client.Concurrency = 1
 
-	return client
+	servicesRouter.HandleFunc("/__health", fthealth.Handler(healthCheck))
+	g2gHandler := status.NewGoodToGoHandler(gtg.StatusChecker(handler.GTG))
+	servicesRouter.HandleFunc(status.GTGPath, g2gHandler)
+
+	servicesRouter.HandleFunc("/transformers/organisations/__count", handler.getOrgCount).Methods("GET")
+	servicesRouter.HandleFunc("/transformers/organisations/__ids", handler.getOrgIds).Methods("GET")
+	servicesRouter.Handle("/transformers/organisations/__reload", auth.protect(http.HandlerFunc(handler.reloadOrgs))).Methods("POST")
+	servicesRouter.Handle("/transformers/organisations/__reload/{id}", auth.protect(http.HandlerFunc(handler.cancelReload))).Methods("DELETE")
+	servicesRouter.HandleFunc("/transformers/organisations/__conflicts", handler.getConflicts).Methods("GET")
+	servicesRouter.HandleFunc("/transformers/organisations/__validation", handler.getValidation).Methods("GET")
+
+	servicesRouter.HandleFunc("/transformers/organisations/{uuid:[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}}", handler.getOrgByUUID).Methods("GET")
+	servicesRouter.HandleFunc("/transformers/organisations", handler.getOrgs).Methods("GET")
+
+	var h http.Handler = limiter.handler(servicesRouter, servicesRouter)
+	h = routeMetricsHandler(servicesRouter, h)
+	h = tracingHandler(servicesRouter, h)
+	h = httphandlers.TransactionAwareRequestLoggingHandler(log.StandardLogger(), h)
+	h = httphandlers.HTTPMetricsHandler(metrics.DefaultRegistry, h)
+	return h
 }
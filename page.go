@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// maxPageLimit bounds the number of orgs a single page lists
+const maxPageLimit = 10000
+
+// orgPage selects a page of the orgs getOrgs lists, the zero value selecting all of them
+type orgPage struct {
+	limit int
+	after string
+}
+
+// parseOrgPage reads the limit and after query parameters, after being the UUID the previous page ended with
+func parseOrgPage(query url.Values) (orgPage, error) {
+	p := orgPage{after: query.Get("after")}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return orgPage{}, fmt.Errorf("Invalid limit [%v], expected a number from 1 to %d", limit, maxPageLimit)
+		}
+		p.limit = n
+	}
+	return p, nil
+}
+
+// full tells whether a page holding count orgs already has all it can take
+func (p orgPage) full(count int) bool {
+	return p.limit > 0 && count >= p.limit
+}
+
+// nextURL gives the URL of the page after the one ending with the org of the given UUID
+func nextURL(u *url.URL, last string) string {
+	query := u.Query()
+	query.Set("after", last)
+	next := url.URL{Path: u.Path, RawQuery: query.Encode()}
+	return next.String()
+}
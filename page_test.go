@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOrgPage(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name  string
+		query string
+		page  orgPage
+		err   bool
+	}{
+		{"No page", "", orgPage{}, false},
+		{"Limit", "limit=10", orgPage{limit: 10}, false},
+		{"Limit after an org", "limit=10&after=" + testUUID, orgPage{limit: 10, after: testUUID}, false},
+		{"Limit not a number", "limit=ten", orgPage{}, true},
+		{"Limit zero", "limit=0", orgPage{}, true},
+		{"Limit too high", "limit=10001", orgPage{}, true},
+	}
+	for _, test := range tests {
+		query, _ := url.ParseQuery(test.query)
+		page, err := parseOrgPage(query)
+		assert.Equal(test.err, err != nil, test.name)
+		assert.Equal(test.page, page, test.name)
+	}
+}
+
+func TestNextURL(t *testing.T) {
+	u, _ := url.Parse("http://localhost:8080/transformers/organisations?type=Organisation&limit=2&after=a")
+	assert.Equal(t, "/transformers/organisations?after=b&limit=2&type=Organisation", nextURL(u, "b"))
+}
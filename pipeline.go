@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Financial-Times/tme-reader/tmereader"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// pipelineConfig sizes the worker pools a load fetches, transforms and stores pages with.
+// Stages hand pages over channels buffering one page per worker of the next stage, so a slow stage
+// holds back the earlier ones instead of letting pages pile up in memory. Zero means one worker.
+type pipelineConfig struct {
+	fetchers     int
+	transformers int
+	writers      int
+}
+
+func (c pipelineConfig) String() string {
+	return fmt.Sprintf("fetchers=%d transformers=%d writers=%d", workers(c.fetchers), workers(c.transformers), workers(c.writers))
+}
+
+func workers(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+type fetchedPage struct {
+	offset int
+	terms  []interface{}
+}
+
+type transformedPage struct {
+	offset int
+	orgs   []org
+}
+
+// refresher is implemented by repositories that take a snapshot of their terms when a load starts
+type refresher interface {
+	refresh() error
+}
+
+func refreshRepository(repo tmereader.Repository) error {
+	if r, ok := repo.(refresher); ok {
+		return r.refresh()
+	}
+	return nil
+}
+
+// fetchProgress is shared by the fetchers to find the last page and give up after too many failures.
+// Failures are counted in a row by page offset, whatever the order parallel fetchers finish them in.
+type fetchProgress struct {
+	sync.Mutex
+	pageSize int
+	failures map[int]bool
+	end      int
+	abortErr error
+}
+
+func newFetchProgress(pageSize int) *fetchProgress {
+	return &fetchProgress{pageSize: pageSize, failures: make(map[int]bool), end: -1}
+}
+
+// failed records the failure of the page at offset, telling whether the load should stop
+func (p *fetchProgress) failed(policy pageRetryPolicy, offset int, err error) bool {
+	p.Lock()
+	defer p.Unlock()
+	p.failures[offset] = true
+	inARow := 1
+	for o := offset - p.pageSize; p.failures[o]; o -= p.pageSize {
+		inARow++
+	}
+	for o := offset + p.pageSize; p.failures[o]; o += p.pageSize {
+		inARow++
+	}
+	if p.abortErr == nil && policy.abort(inARow) {
+		p.abortErr = fmt.Errorf("Aborted after %d pages in a row failed: %v", inARow, err)
+	}
+	return p.abortErr != nil
+}
+
+func (p *fetchProgress) reachedEnd(offset int) {
+	p.Lock()
+	defer p.Unlock()
+	if p.end < 0 || offset < p.end {
+		p.end = offset
+	}
+}
+
+// skip tells whether a fetcher should leave out the page at offset, as the load was aborted or the page is past the last one.
+// Pages before the last one are still fetched, whichever fetcher found the end first.
+func (p *fetchProgress) skip(offset int) bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.abortErr != nil || (p.end >= 0 && offset >= p.end)
+}
+
+// startWorkers runs n copies of work, calling done once all of them have returned
+func startWorkers(n int, work func(), done func()) {
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			work()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		done()
+	}()
+}
+
+// runPipeline fetches pages from offset until TME returns an empty one, transforming and storing them in the staging bucket.
+// It returns once every fetched page is stored, with an error if the load was cancelled or aborted.
+func (s *orgServiceImpl) runPipeline(ctx context.Context, l *loadTracker, offset int) error {
+	progress := newFetchProgress(s.maxTmeRecords)
+	stopped := make(chan struct{})
+	var once sync.Once
+	stop := func() { once.Do(func() { close(stopped) }) }
+
+	offsets := make(chan int)
+	go func() {
+		defer close(offsets)
+		for next := offset; ; next += s.maxTmeRecords {
+			select {
+			case offsets <- next:
+			case <-stopped:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	fetched := make(chan fetchedPage, workers(s.pipeline.transformers))
+	startWorkers(workers(s.pipeline.fetchers), func() {
+		for o := range offsets {
+			if progress.skip(o) {
+				continue
+			}
+			s.fetchPage(ctx, l, o, progress, stop, fetched)
+		}
+	}, func() { close(fetched) })
+
+	transformed := make(chan transformedPage, workers(s.pipeline.writers))
+	startWorkers(workers(s.pipeline.transformers), func() {
+		for p := range fetched {
+			transformed <- s.transformPage(l, p)
+		}
+	}, func() { close(transformed) })
+
+	stored := make(chan struct{})
+	startWorkers(workers(s.pipeline.writers), func() {
+		for p := range transformed {
+			s.storeOrgToCache(ctx, p.orgs, p.offset, l)
+		}
+	}, func() { close(stored) })
+	<-stored
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("Load cancelled: %w", err)
+	}
+	if progress.abortErr != nil {
+		return progress.abortErr
+	}
+	// fetchers running ahead may have failed on pages past the last one
+	l.ignoreFailuresAfter(progress.end)
+	return nil
+}
+
+func (s *orgServiceImpl) fetchPage(ctx context.Context, l *loadTracker, offset int, progress *fetchProgress, stop func(), fetched chan<- fetchedPage) {
+	pageLog := l.log.WithField("offset", offset)
+	pageLog.Info("Getting terms")
+	_, span := tracer.Start(ctx, "tme.fetchPage", trace.WithAttributes(attribute.Int("tme.offset", offset)))
+	terms, err := s.retry.fetch(ctx, pageLog, s.repository, offset)
+	span.SetAttributes(attribute.Int("tme.terms", len(terms)))
+	endSpan(span, err)
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		pageLog.WithError(err).Error("Error getting terms")
+		l.pageFailed(offset, err)
+		if progress.failed(s.retry, offset, err) {
+			stop()
+		}
+		return
+	}
+	if len(terms) < 1 {
+		pageLog.Info("Finished fetching organisations from TME, waiting for the pages to be stored")
+		progress.reachedEnd(offset)
+		stop()
+		return
+	}
+	fetched <- fetchedPage{offset: offset, terms: terms}
+}
+
+func (s *orgServiceImpl) transformPage(l *loadTracker, page fetchedPage) transformedPage {
+	orgs := make([]org, 0, len(page.terms))
+	for _, iTerm := range page.terms {
+		orgs = append(orgs, transformOrg(iTerm.(term), s.taxonomyName))
+	}
+	l.log.WithFields(log.Fields{"offset": page.offset, "count": len(orgs)}).Debug("Transformed page")
+	return transformedPage{offset: page.offset, orgs: orgs}
+}
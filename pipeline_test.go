@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// pagedRepo serves total orgs in pages of pageSize, all with the same TME identifier when conflicting
+type pagedRepo struct {
+	total       int
+	pageSize    int
+	delay       time.Duration
+	conflicting bool
+}
+
+func (r *pagedRepo) GetTmeTermsFromIndex(startRecord int) ([]interface{}, error) {
+	time.Sleep(r.delay)
+	var terms []interface{}
+	for i := startRecord; i < r.total && i < startRecord+r.pageSize; i++ {
+		rawID := fmt.Sprintf("org-%d", i)
+		if r.conflicting {
+			rawID = "org"
+		}
+		terms = append(terms, term{CanonicalName: fmt.Sprintf("Org %d", i), RawID: rawID})
+	}
+	return terms, nil
+}
+
+func (r *pagedRepo) GetTmeTermById(uuid string) (interface{}, error) {
+	return nil, nil
+}
+
+func newPipelineTestService(repo *pagedRepo, cacheFileName string, pipeline pipelineConfig) *orgServiceImpl {
+	return &orgServiceImpl{repository: repo, taxonomyName: "ON", maxTmeRecords: repo.pageSize, cacheFileName: cacheFileName,
+		orgCache: newOrgLRU(0), conflicts: newConflictReport(), validation: defaultValidation(), pipeline: pipeline}
+}
+
+func TestPipelineLoadsEveryPage(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name     string
+		pipeline pipelineConfig
+	}{
+		{"Defaults", pipelineConfig{}},
+		{"Single workers", pipelineConfig{fetchers: 1, transformers: 1, writers: 1}},
+		{"Parallel workers", pipelineConfig{fetchers: 4, transformers: 3, writers: 2}},
+	}
+
+	for _, test := range tests {
+		s := newPipelineTestService(&pagedRepo{total: 95, pageSize: 10, delay: time.Millisecond}, testCacheFile(t), test.pipeline)
+		assert.NoError(s.init(), fmt.Sprintf("%s: Unexpected error", test.name))
+		count, _ := s.orgCount(context.Background())
+		assert.Equal(95, count, fmt.Sprintf("%s: Wrong count", test.name))
+		status, _ := s.lastLoadStatus()
+		assert.Equal(loadSucceeded, status.Outcome, fmt.Sprintf("%s: Wrong outcome", test.name))
+		s.shutdown(context.Background())
+	}
+}
+
+func TestPipelineKeepsTheSameOrgOnConflicts(t *testing.T) {
+	assert := assert.New(t)
+	for i := 0; i < 5; i++ {
+		s := newPipelineTestService(&pagedRepo{total: 40, pageSize: 4, conflicting: true}, testCacheFile(t), pipelineConfig{fetchers: 4, transformers: 4, writers: 4})
+		assert.NoError(s.init())
+		ids, _ := s.orgIds(context.Background())
+		if assert.Len(ids, 1) {
+			kept, _, _ := s.getOrgByUUID(context.Background(), ids[0].UUID)
+			assert.Equal("Org 0", kept.PrefLabel, "Org with the lowest encoding should be kept whatever order the writers ran in")
+		}
+		conflicts := s.uuidConflicts()
+		if assert.Len(conflicts, 1) {
+			assert.Len(conflicts[0].Orgs, 40)
+			assert.Equal("Org 0", conflicts[0].Orgs[0].PrefLabel, "Kept org should be listed first")
+		}
+		assert.Equal(40, s.validationSummary().Checked, "Each org should be validated once")
+		s.shutdown(context.Background())
+	}
+}
+
+func TestPipelineStoresEveryPageWithFetchersAheadOfTheEnd(t *testing.T) {
+	assert := assert.New(t)
+	for i := 0; i < 10; i++ {
+		s := newPipelineTestService(&pagedRepo{total: 50, pageSize: 1}, testCacheFile(t), pipelineConfig{fetchers: 32, transformers: 4, writers: 4})
+		assert.NoError(s.init())
+		count, _ := s.orgCount(context.Background())
+		assert.Equal(50, count, "Pages fetched while another fetcher found the end should be stored")
+		s.shutdown(context.Background())
+	}
+}
+
+func TestFetchProgressSkip(t *testing.T) {
+	assert := assert.New(t)
+	progress := newFetchProgress(10)
+	assert.False(progress.skip(50), "Nothing is skipped before the end is found")
+	progress.reachedEnd(30)
+	assert.False(progress.skip(20), "Pages before the end should be fetched")
+	assert.True(progress.skip(30))
+	assert.True(progress.skip(40))
+	progress.failed(pageRetryPolicy{maxConsecutiveFailures: 1}, 0, errors.New("TME unavailable"))
+	assert.True(progress.skip(20), "Nothing is fetched once the load is aborted")
+}
+
+func TestFetchProgressCountsFailuresInARowByOffset(t *testing.T) {
+	assert := assert.New(t)
+	policy := pageRetryPolicy{maxConsecutiveFailures: 2}
+	tests := []struct {
+		name    string
+		offsets []int
+		aborted bool
+	}{
+		{"Single failure", []int{10}, false},
+		{"Pages apart failing one after the other", []int{30, 10, 50}, false},
+		{"Adjacent pages", []int{10, 20}, true},
+		{"Adjacent pages failing out of order", []int{30, 10, 20}, true},
+	}
+	for _, test := range tests {
+		progress := newFetchProgress(10)
+		aborted := false
+		for _, o := range test.offsets {
+			aborted = progress.failed(policy, o, errors.New("TME unavailable"))
+		}
+		assert.Equal(test.aborted, aborted, test.name)
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	log.SetLevel(log.WarnLevel)
+	defer log.SetLevel(log.InfoLevel)
+	for _, total := range []int{10000, 50000} {
+		for _, pipeline := range []pipelineConfig{{}, {fetchers: 4, transformers: 2, writers: 2}, {fetchers: 8, transformers: 4, writers: 4}} {
+			b.Run(fmt.Sprintf("orgs=%d/%v", total, pipeline), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					// the delay stands for the TME round trip
+					s := newPipelineTestService(&pagedRepo{total: total, pageSize: 1000, delay: 20 * time.Millisecond}, testCacheFile(b), pipeline)
+					if err := s.init(); err != nil {
+						b.Fatal(err)
+					}
+					s.shutdown(context.Background())
+				}
+				b.ReportMetric(float64(total*b.N)/b.Elapsed().Seconds(), "orgs/s")
+			})
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Financial-Times/tme-reader/tmereader"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests by route template, method and status code",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+	loadDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "org_load_duration_seconds",
+		Help:    "Duration of organisation loads by outcome",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+	}, []string{"outcome"})
+	tmePageDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "tme_page_fetch_duration_seconds",
+		Help:    "Latency of fetching a page of terms from TME",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 12),
+	})
+	tmePageErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tme_page_fetch_errors_total",
+		Help: "Number of failed attempts to fetch a page of terms from TME",
+	})
+	tmeClientRetries = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tme_client_retries_total",
+		Help: "Number of failed TME requests the client retried",
+	})
+	tmeClientFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tme_client_failures_total",
+		Help: "Number of TME requests the client gave up on after its last retry",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(httpRequestDuration, loadDuration, tmePageDuration, tmePageErrors, tmeClientRetries, tmeClientFailures)
+}
+
+// registerServiceMetrics exposes the number of cached orgs and the size of the cache file
+func registerServiceMetrics(s orgsService, cacheFileName string) {
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "orgs_cached",
+		Help: "Number of organisations in the cache",
+	}, func() float64 {
+		if !s.isInitialised() {
+			return 0
+		}
+		count, err := s.orgCount(context.Background())
+		if err != nil {
+			return 0
+		}
+		return float64(count)
+	}))
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cache_file_size_bytes",
+		Help: "Size of the cache file",
+	}, func() float64 {
+		info, err := os.Stat(cacheFileName)
+		if err != nil {
+			return 0
+		}
+		return float64(info.Size())
+	}))
+}
+
+// instrumentedRepository records the latency and errors of TME page fetches
+type instrumentedRepository struct {
+	tmereader.Repository
+}
+
+func (r instrumentedRepository) GetTmeTermsFromIndex(startRecord int) ([]interface{}, error) {
+	start := time.Now()
+	terms, err := r.Repository.GetTmeTermsFromIndex(startRecord)
+	tmePageDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		tmePageErrors.Inc()
+	}
+	return terms, err
+}
+
+func (r instrumentedRepository) refresh() error {
+	return refreshRepository(r.Repository)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// routeTemplate gives the path template of the router route r matches, without the patterns of its variables
+func routeTemplate(router *mux.Router, r *http.Request) string {
+	var match mux.RouteMatch
+	if router.Match(r, &match) && match.Route != nil {
+		if tmpl, err := match.Route.GetPathTemplate(); err == nil {
+			return stripVariablePatterns(tmpl)
+		}
+	}
+	return "unmatched"
+}
+
+// stripVariablePatterns turns /{uuid:[0-9a-f]{8}} into /{uuid}
+func stripVariablePatterns(tmpl string) string {
+	var b strings.Builder
+	depth := 0
+	inPattern := false
+	for _, c := range tmpl {
+		switch {
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				inPattern = false
+			}
+		case c == ':' && depth == 1:
+			inPattern = true
+		}
+		if !inPattern {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// routeMetricsHandler records the latency of requests labelled with the template of the router route they match
+func routeMetricsHandler(router *mux.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(router, r)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+		httpRequestDuration.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentedRepositoryCountsErrors(t *testing.T) {
+	assert := assert.New(t)
+	before := testutil.ToFloat64(tmePageErrors)
+
+	instrumentedRepository{&dummyRepo{terms: []term{term{RawID: "1"}}}}.GetTmeTermsFromIndex(0)
+	assert.Equal(before, testutil.ToFloat64(tmePageErrors), "Successful fetch should not count as an error")
+
+	_, err := instrumentedRepository{&dummyRepo{err: errors.New("TME unavailable")}}.GetTmeTermsFromIndex(0)
+	assert.Error(err)
+	assert.Equal(before+1, testutil.ToFloat64(tmePageErrors), "Failed fetch should count as an error")
+}
+
+func TestRouteMetrics(t *testing.T) {
+	assert := assert.New(t)
+	r := router(&dummyService{initialised: true, orgs: []org{org{UUID: testUUID}}})
+	h := routeMetricsHandler(r, r)
+
+	routed := `http_request_duration_seconds_count{method="GET",route="/transformers/organisations/{uuid}",status="404"}`
+	unmatched := `http_request_duration_seconds_count{method="GET",route="unmatched",status="404"}`
+	routedBefore, unmatchedBefore := metricValue(t, routed), metricValue(t, unmatched)
+
+	h.ServeHTTP(httptest.NewRecorder(), newRequest("GET", fmt.Sprintf("/transformers/organisations/%s", testUUID)))
+	h.ServeHTTP(httptest.NewRecorder(), newRequest("GET", "/unknown"))
+
+	assert.Equal(routedBefore+1, metricValue(t, routed))
+	assert.Equal(unmatchedBefore+1, metricValue(t, unmatched))
+}
+
+// metricValue reads a sample from /metrics, 0 when it is not there yet
+func metricValue(t *testing.T, sample string) float64 {
+	rec := httptest.NewRecorder()
+	promhttp.Handler().ServeHTTP(rec, newRequest("GET", "/metrics"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if strings.HasPrefix(line, sample+" ") {
+			v, err := strconv.ParseFloat(strings.TrimPrefix(line, sample+" "), 64)
+			assert.NoError(t, err)
+			return v
+		}
+	}
+	return 0
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	idleClientTimeout     = 3 * time.Minute
+	maxRateLimitedClients = 100000
+)
+
+// routeLimit is the token bucket every client gets on a route: rate requests a second, in bursts of up to burst
+type routeLimit struct {
+	rate  rate.Limit
+	burst int
+}
+
+// parseRateLimits reads limits given as route=rate/burst, the route being a router path template
+func parseRateLimits(specs []string) (map[string]routeLimit, error) {
+	limits := make(map[string]routeLimit)
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid rate limit [%v], expected route=rate/burst", spec)
+		}
+		values := strings.SplitN(parts[1], "/", 2)
+		if len(values) != 2 {
+			return nil, fmt.Errorf("Invalid rate limit [%v], expected route=rate/burst", spec)
+		}
+		r, err := strconv.ParseFloat(values[0], 64)
+		if err != nil || r <= 0 {
+			return nil, fmt.Errorf("Invalid rate in [%v]", spec)
+		}
+		burst, err := strconv.Atoi(values[1])
+		if err != nil || burst < 1 {
+			return nil, fmt.Errorf("Invalid burst in [%v]", spec)
+		}
+		limits[parts[0]] = routeLimit{rate: rate.Limit(r), burst: burst}
+	}
+	return limits, nil
+}
+
+// parseTrustedProxies reads the addresses or CIDR ranges of the proxies whose X-Forwarded-For is believed
+func parseTrustedProxies(specs []string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		if !strings.Contains(spec, "/") {
+			ip := net.ParseIP(spec)
+			if ip == nil {
+				return nil, fmt.Errorf("Invalid trusted proxy [%v], expected an IP address or a CIDR range", spec)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(spec)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid trusted proxy [%v], expected an IP address or a CIDR range", spec)
+		}
+		proxies = append(proxies, ipNet)
+	}
+	return proxies, nil
+}
+
+type clientBucket struct {
+	key      string
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiter keeps a token bucket per client and limited route, up to maxClients of them.
+// Buckets are kept from the most to the least recently used, the idle ones being forgotten and the least recently used evicted when full.
+type rateLimiter struct {
+	sync.Mutex
+	limits         map[string]routeLimit
+	trustedProxies []*net.IPNet
+	maxClients     int
+	clients        map[string]*list.Element
+	recent         *list.List
+	now            func() time.Time
+}
+
+func newRateLimiter(limits map[string]routeLimit, trustedProxies []*net.IPNet) *rateLimiter {
+	return &rateLimiter{limits: limits, trustedProxies: trustedProxies, maxClients: maxRateLimitedClients, clients: make(map[string]*list.Element), recent: list.New(), now: time.Now}
+}
+
+// allow takes a token from the client's bucket for the route, or tells how long until one is available
+func (l *rateLimiter) allow(route string, client string) (bool, time.Duration) {
+	limit, ok := l.limits[route]
+	if !ok {
+		return true, 0
+	}
+	l.Lock()
+	defer l.Unlock()
+	now := l.now()
+	for oldest := l.recent.Back(); oldest != nil && now.Sub(oldest.Value.(*clientBucket).lastSeen) > idleClientTimeout; oldest = l.recent.Back() {
+		l.forget(oldest)
+	}
+	key := route + " " + client
+	e, ok := l.clients[key]
+	if !ok {
+		if l.recent.Len() >= l.maxClients {
+			l.forget(l.recent.Back())
+		}
+		e = l.recent.PushFront(&clientBucket{key: key, limiter: rate.NewLimiter(limit.rate, limit.burst)})
+		l.clients[key] = e
+	}
+	l.recent.MoveToFront(e)
+	b := e.Value.(*clientBucket)
+	b.lastSeen = now
+	reservation := b.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+func (l *rateLimiter) forget(e *list.Element) {
+	l.recent.Remove(e)
+	delete(l.clients, e.Value.(*clientBucket).key)
+}
+
+// clientID identifies the caller by its remote address. Behind a trusted proxy, it is the right-most address
+// of X-Forwarded-For not belonging to a trusted proxy, as the entries left of it may be set by the client.
+func (l *rateLimiter) clientID(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !l.trusted(host) {
+		return host
+	}
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(forwarded[i])
+		if addr == "" {
+			continue
+		}
+		if !l.trusted(addr) {
+			return addr
+		}
+		host = addr
+	}
+	return host
+}
+
+func (l *rateLimiter) trusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range l.trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// handler rejects requests over the limit of the router route they match with a 429 and a Retry-After in seconds
+func (l *rateLimiter) handler(router *mux.Router, next http.Handler) http.Handler {
+	if len(l.limits) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(router, r)
+		client := l.clientID(r)
+		if ok, wait := l.allow(route, client); !ok {
+			log.WithFields(log.Fields{"route": route, "client": client}).Warn("Rate limit exceeded")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeJSONError(w, r, http.StatusTooManyRequests, codeRateLimited, "Rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
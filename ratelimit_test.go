@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	assert := assert.New(t)
+	limits, err := parseRateLimits([]string{"/transformers/organisations/{uuid}=0.5/10"})
+	assert.NoError(err)
+	assert.Equal(map[string]routeLimit{"/transformers/organisations/{uuid}": routeLimit{rate: 0.5, burst: 10}}, limits)
+
+	for _, spec := range []string{"/transformers/organisations", "/transformers/organisations=10", "/transformers/organisations=fast/10", "/transformers/organisations=10/0"} {
+		_, err := parseRateLimits([]string{spec})
+		assert.Error(err, fmt.Sprintf("%s: Expected an error", spec))
+	}
+}
+
+func TestRateLimitHandler(t *testing.T) {
+	assert := assert.New(t)
+	router := mux.NewRouter()
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	router.HandleFunc("/transformers/organisations/{uuid:[0-9a-f-]{36}}", ok)
+	router.HandleFunc("/transformers/organisations/__count", ok)
+	limiter := newRateLimiter(map[string]routeLimit{"/transformers/organisations/{uuid}": routeLimit{rate: 1, burst: 2}}, nil)
+	now := time.Unix(1700000000, 0)
+	limiter.now = func() time.Time { return now }
+	h := limiter.handler(router, router)
+
+	get := func(path string, client string) *httptest.ResponseRecorder {
+		req := newRequest("GET", path)
+		req.RemoteAddr = client + ":51000"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	org := "/transformers/organisations/" + testUUID
+
+	tests := []struct {
+		name       string
+		path       string
+		client     string
+		advance    time.Duration
+		statusCode int
+		retryAfter string
+	}{
+		{"Within burst", org, "1.1.1.1", 0, http.StatusOK, ""},
+		{"Burst used up", org, "1.1.1.1", 0, http.StatusOK, ""},
+		{"Over the limit", org, "1.1.1.1", 0, http.StatusTooManyRequests, "1"},
+		{"Other client has its own bucket", org, "2.2.2.2", 0, http.StatusOK, ""},
+		{"Unlimited route", "/transformers/organisations/__count", "1.1.1.1", 0, http.StatusOK, ""},
+		{"Token refilled", org, "1.1.1.1", time.Second, http.StatusOK, ""},
+		{"Over the limit again", org, "1.1.1.1", 0, http.StatusTooManyRequests, "1"},
+	}
+
+	for _, test := range tests {
+		now = now.Add(test.advance)
+		rec := get(test.path, test.client)
+		assert.Equal(test.statusCode, rec.Code, fmt.Sprintf("%s: Wrong response code", test.name))
+		assert.Equal(test.retryAfter, rec.Header().Get("Retry-After"), fmt.Sprintf("%s: Wrong Retry-After", test.name))
+	}
+
+	now = now.Add(2 * idleClientTimeout)
+	get(org, "3.3.3.3")
+	assert.Len(limiter.clients, 1, "Idle clients should be forgotten")
+}
+
+func TestRateLimitClientID(t *testing.T) {
+	assert := assert.New(t)
+	proxies, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	assert.NoError(err)
+	limiter := newRateLimiter(nil, proxies)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		client     string
+	}{
+		{"Direct client", "1.1.1.1:51000", nil, "1.1.1.1"},
+		{"Forwarded header of an untrusted client ignored", "1.1.1.1:51000", []string{"2.2.2.2"}, "1.1.1.1"},
+		{"Trusted proxy", "10.0.0.1:51000", []string{"2.2.2.2"}, "2.2.2.2"},
+		{"Entries set by the client ignored", "10.0.0.1:51000", []string{"3.3.3.3, 2.2.2.2"}, "2.2.2.2"},
+		{"Chain of trusted proxies", "10.0.0.1:51000", []string{"3.3.3.3, 2.2.2.2, 192.168.1.1", "10.0.0.2"}, "2.2.2.2"},
+		{"Trusted proxy without forwarded header", "10.0.0.1:51000", nil, "10.0.0.1"},
+	}
+	for _, test := range tests {
+		req := newRequest("GET", "/transformers/organisations")
+		req.RemoteAddr = test.remoteAddr
+		for _, f := range test.forwarded {
+			req.Header.Add("X-Forwarded-For", f)
+		}
+		assert.Equal(test.client, limiter.clientID(req), test.name)
+	}
+
+	_, err = parseTrustedProxies([]string{"proxy.example.com"})
+	assert.Error(err, "Host names should be rejected")
+}
+
+func TestRateLimitSpoofedForwardedHeader(t *testing.T) {
+	assert := assert.New(t)
+	router := mux.NewRouter()
+	router.HandleFunc("/transformers/organisations", func(w http.ResponseWriter, r *http.Request) {})
+	limiter := newRateLimiter(map[string]routeLimit{"/transformers/organisations": routeLimit{rate: 1, burst: 2}}, nil)
+	limiter.now = func() time.Time { return time.Unix(1700000000, 0) }
+	h := limiter.handler(router, router)
+
+	var codes []int
+	for i := 0; i < 4; i++ {
+		req := newRequest("GET", "/transformers/organisations")
+		req.RemoteAddr = "1.1.1.1:51000"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("9.9.9.%d", i))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+	assert.Equal([]int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}, codes, "Spoofed forwarded addresses should share the client's bucket")
+	assert.Len(limiter.clients, 1)
+}
+
+func TestRateLimitClientsBounded(t *testing.T) {
+	assert := assert.New(t)
+	limiter := newRateLimiter(map[string]routeLimit{"/transformers/organisations": routeLimit{rate: 1, burst: 1}}, nil)
+	limiter.maxClients = 3
+	now := time.Unix(1700000000, 0)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Millisecond)
+		limiter.allow("/transformers/organisations", fmt.Sprintf("1.1.1.%d", i))
+	}
+	assert.Len(limiter.clients, 3)
+	assert.Equal(3, limiter.recent.Len())
+	for i := 7; i < 10; i++ {
+		assert.Contains(limiter.clients, fmt.Sprintf("/transformers/organisations 1.1.1.%d", i), "The most recent clients should be kept")
+	}
+}
+
+func TestStripVariablePatterns(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("/transformers/organisations/{uuid}", stripVariablePatterns("/transformers/organisations/{uuid:[0-9a-fA-F]{8}-[0-9a-fA-F]{4}}"))
+	assert.Equal("/transformers/organisations/__reload/{id}", stripVariablePatterns("/transformers/organisations/__reload/{id}"))
+}
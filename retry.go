@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/Financial-Times/tme-reader/tmereader"
+	log "github.com/sirupsen/logrus"
+)
+
+// pageRetryPolicy controls how a load copes with TME pages failing to be fetched.
+// The zero value fetches each page once and aborts the load on the first failure.
+type pageRetryPolicy struct {
+	retries                int
+	backoff                time.Duration
+	maxConsecutiveFailures int
+}
+
+// fetch gets a page of terms, retrying with an exponential backoff until ctx is done
+func (p pageRetryPolicy) fetch(ctx context.Context, logger *log.Entry, repo tmereader.Repository, offset int) ([]interface{}, error) {
+	terms, err := repo.GetTmeTermsFromIndex(offset)
+	for attempt := 0; err != nil && attempt < p.retries; attempt++ {
+		wait := p.backoff * time.Duration(1<<uint(attempt))
+		logger.WithFields(log.Fields{"offset": offset, "attempt": attempt + 1, "wait": wait}).WithError(err).Warn("Error getting terms, retrying")
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		terms, err = repo.GetTmeTermsFromIndex(offset)
+	}
+	return terms, err
+}
+
+// abort tells whether a load should give up after this many pages failed in a row
+func (p pageRetryPolicy) abort(consecutiveFailures int) bool {
+	return consecutiveFailures >= p.maxConsecutiveFailures
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// serve runs the HTTP server until it fails or a signal arrives on stop. On a signal it stops accepting requests,
+// drains the in-flight ones and shuts the service down, all within timeout. When the server fails, the service is
+// shut down within timeout as well.
+func serve(srv *http.Server, l net.Listener, s orgsService, timeout time.Duration, stop <-chan os.Signal) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(l)
+	}()
+
+	select {
+	case err := <-errs:
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if shutdownErr := s.shutdown(ctx); shutdownErr != nil {
+			log.WithError(shutdownErr).Error("Error shutting down after the server failed")
+		}
+		return err
+	case sig := <-stop:
+		log.WithFields(log.Fields{"signal": sig, "timeout": timeout}).Info("Shutting down")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.WithError(err).Error("Error draining in-flight requests")
+	}
+	if err := s.shutdown(ctx); err != nil {
+		return err
+	}
+	log.Info("Shut down cleanly")
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeDrainsRequestsOnSignal(t *testing.T) {
+	assert := assert.New(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+
+	started := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("done"))
+	})}
+	service := &dummyService{}
+	stop := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(srv, listener, service, 5*time.Second, stop)
+	}()
+
+	responses := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			responses <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		responses <- string(body)
+	}()
+	<-started
+	stop <- syscall.SIGTERM
+
+	assert.Equal("done", <-responses, "In-flight request should be drained")
+	assert.NoError(<-served)
+	assert.True(service.stopped, "Service should be shut down")
+	_, err = net.Dial("tcp", listener.Addr().String())
+	assert.Error(err, "Server should stop accepting connections")
+}
+
+func TestServeShutsDownWithinTimeoutWhenServerFails(t *testing.T) {
+	assert := assert.New(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+	listener.Close()
+
+	service := &dummyService{hangs: true}
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(&http.Server{}, listener, service, 100*time.Millisecond, make(chan os.Signal))
+	}()
+
+	select {
+	case err := <-served:
+		assert.Error(err, "Server failure should be returned")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown after a server failure should not outlast its timeout")
+	}
+	assert.True(service.stopped, "Service should be shut down")
+}
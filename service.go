@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,23 +10,39 @@ import (
 	"time"
 
 	"github.com/Financial-Times/tme-reader/tmereader"
-	log "github.com/sirupsen/logrus"
 	"github.com/boltdb/bolt"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	cacheBucket = "org"
+	cacheBucket   = "org"
+	stagingBucket = "org_staging"
+	// metaBucket holds when each cached org last changed, as RFC 3339 times keyed by UUID
+	metaBucket = "org_meta"
+)
+
+var (
+	errNothingToResume = errors.New("No partial load to resume")
+	errLoadRunning     = errors.New("A load is already running")
+	errShuttingDown    = errors.New("Service is shutting down")
 )
 
 type orgsService interface {
-	getOrgs() ([]orgLink, error)
-	getOrgByUUID(uuid string) (org, bool, error)
+	getOrgs(ctx context.Context, filter orgFilter, page orgPage) ([]orgLink, string, error)
+	getOrgByUUID(ctx context.Context, uuid string) (org, bool, error)
 	isInitialised() bool
 	isDataLoaded() bool
-	shutdown() error
-	orgCount() (int, error)
-	orgIds() ([]orgUUID, error)
-	orgReload() error
+	shutdown(ctx context.Context) error
+	orgCount(ctx context.Context) (int, error)
+	orgIds(ctx context.Context) ([]orgUUID, error)
+	orgReload(ctx context.Context, resume bool) (string, error)
+	cancelLoad(id string) bool
+	resumeOffset() (int, bool)
+	uuidConflicts() []uuidConflict
+	validationSummary() validationSummary
+	lastLoadStatus() (loadStatus, bool)
 }
 
 type orgServiceImpl struct {
@@ -37,16 +55,37 @@ type orgServiceImpl struct {
 	dataLoaded    bool
 	cacheFileName string
 	db            *bolt.DB
+	orgCache      *orgLRU
+	conflicts     *conflictReport
+	validation    *validationReport
+	countGuard    countDropGuard
+	lastLoad      *loadStatus
+	retry         pageRetryPolicy
+	pipeline      pipelineConfig
+	resumeFrom    int
+	resumable     bool
+	running       *runningLoad
+	loads         sync.WaitGroup
+	closed        bool
+
+	// reports of the running or partial load, published once its orgs are swapped in
+	stagedConflicts  *conflictReport
+	stagedValidation *validationReport
+}
+
+// runningLoad identifies the load in progress so it can be cancelled
+type runningLoad struct {
+	id     string
+	cancel context.CancelFunc
 }
 
-func newOrgService(repo tmereader.Repository, baseURL string, taxonomyName string, maxTmeRecords int, cacheFileName string) orgsService {
-	s := &orgServiceImpl{repository: repo, baseURL: baseURL, taxonomyName: taxonomyName, maxTmeRecords: maxTmeRecords, initialised: false, dataLoaded: false, cacheFileName: cacheFileName}
+func newOrgService(repo tmereader.Repository, baseURL string, taxonomyName string, maxTmeRecords int, cacheFileName string, lruSize int, validation *validationReport, countGuard countDropGuard, retry pageRetryPolicy, pipeline pipelineConfig) orgsService {
+	s := &orgServiceImpl{repository: repo, baseURL: baseURL, taxonomyName: taxonomyName, maxTmeRecords: maxTmeRecords, initialised: false, dataLoaded: false, cacheFileName: cacheFileName, orgCache: newOrgLRU(lruSize), conflicts: newConflictReport(), validation: validation, countGuard: countGuard, retry: retry, pipeline: pipeline}
 	go func(service *orgServiceImpl) {
 		err := service.init()
 		if err != nil {
 			log.Errorf("Error while creating OrgService: [%v]", err.Error())
 		}
-		s.setDataLoaded(true)
 	}(s)
 	return s
 }
@@ -75,91 +114,412 @@ func (s *orgServiceImpl) setDataLoaded(val bool) {
 	s.dataLoaded = val
 }
 
-func (s *orgServiceImpl) shutdown() error {
+func (s *orgServiceImpl) lastLoadStatus() (loadStatus, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	if s.lastLoad == nil {
+		return loadStatus{}, false
+	}
+	return *s.lastLoad, true
+}
+
+func (s *orgServiceImpl) setLastLoadStatus(status loadStatus) {
+	s.Lock()
+	defer s.Unlock()
+	s.lastLoad = &status
+}
+
+func (s *orgServiceImpl) resumeOffset() (int, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.resumeFrom, s.resumable
+}
+
+func (s *orgServiceImpl) setResumeOffset(offset int, resumable bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.resumeFrom = offset
+	s.resumable = resumable
+}
+
+// shutdown cancels the running load, if any, and closes the DB once the load has stopped or ctx is done, whichever comes first.
+// bolt waits for open transactions before closing, so the cache file is never left mid-write.
+func (s *orgServiceImpl) shutdown(ctx context.Context) error {
+	s.Lock()
+	s.closed = true
+	if s.running != nil {
+		s.running.cancel()
+	}
+	s.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.loads.Wait()
+		close(stopped)
+	}()
+	var err error
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		err = fmt.Errorf("Running load did not stop in time: %v", ctx.Err())
+	}
+
+	s.Lock()
+	defer s.Unlock()
 	if s.db == nil {
+		if err != nil {
+			return err
+		}
 		return errors.New("DB not open")
 	}
-	return s.db.Close()
+	if closeErr := s.db.Close(); closeErr != nil {
+		return closeErr
+	}
+	return err
 }
 
-func (s *orgServiceImpl) openDB() error {
+// startLoad registers a new load unless another one is running or the service is shutting down.
+// The returned context is cancelled by cancelLoad, shutdown or ctx itself.
+func (s *orgServiceImpl) startLoad(ctx context.Context) (context.Context, *loadTracker, error) {
 	s.Lock()
 	defer s.Unlock()
-	var err error
-	s.db, err = bolt.Open(s.cacheFileName, 0600, &bolt.Options{Timeout: 1 * time.Second})
-	if err != nil {
-		log.Errorf("ERROR opening cache file for init: %v", err.Error())
-		return err
+	if s.closed {
+		return nil, nil, errShuttingDown
+	}
+	if s.running != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errLoadRunning, s.running.id)
+	}
+	l := newLoadTracker()
+	ctx, cancel := context.WithCancel(ctx)
+	s.running = &runningLoad{id: l.id, cancel: cancel}
+	s.loads.Add(1)
+	return ctx, l, nil
+}
+
+func (s *orgServiceImpl) finishLoad() {
+	s.Lock()
+	s.running.cancel()
+	s.running = nil
+	s.Unlock()
+	s.loads.Done()
+}
+
+// cancelLoad stops the running load with the given ID, discarding what it staged
+func (s *orgServiceImpl) cancelLoad(id string) bool {
+	s.Lock()
+	defer s.Unlock()
+	if s.running == nil || s.running.id != id {
+		return false
+	}
+	s.running.cancel()
+	return true
+}
+
+func (s *orgServiceImpl) openDB(keepStaging bool) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.db == nil {
+		var err error
+		s.db, err = bolt.Open(s.cacheFileName, 0600, &bolt.Options{Timeout: 1 * time.Second})
+		if err != nil {
+			log.Errorf("ERROR opening cache file for init: %v", err.Error())
+			return err
+		}
 	}
+	// orgs are loaded into a staging bucket and only swapped in once the load is complete, so the previous data is kept until then
 	return s.db.Update(func(tx *bolt.Tx) error {
-		err := tx.DeleteBucket([]byte(cacheBucket))
+		if !keepStaging {
+			err := tx.DeleteBucket([]byte(stagingBucket))
+			if err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
+		}
+		_, err := tx.CreateBucketIfNotExists([]byte(cacheBucket))
 		if err != nil {
-			log.Warnf("Cache bucket [%v] could not be deleted\n", cacheBucket)
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists([]byte(metaBucket))
+		if err != nil {
+			return err
 		}
-		_, err = tx.CreateBucket([]byte(cacheBucket))
+		_, err = tx.CreateBucketIfNotExists([]byte(stagingBucket))
 		return err
 	})
 }
 
-func (s *orgServiceImpl) init() error {
-	var wg sync.WaitGroup
-	responseCount := 0
-
-	log.Printf("Fetching organisations from TME\n")
+func (s *orgServiceImpl) stagedCounts() (previous int, loaded int, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(cacheBucket))
+		staging := tx.Bucket([]byte(stagingBucket))
+		if bucket == nil || staging == nil {
+			return fmt.Errorf("Bucket %v or %v not found!", cacheBucket, stagingBucket)
+		}
+		previous = bucket.Stats().KeyN
+		loaded = staging.Stats().KeyN
+		return nil
+	})
+	return previous, loaded, err
+}
 
-	err := s.openDB()
-	if err != nil {
+// swapStaging replaces the cached orgs with the staged ones in a single bolt transaction, reads carrying on meanwhile
+// from the previous orgs. It then empties the LRU and publishes the reports of the load under the write lock, which
+// waits for the reads begun before the swap, so none of them can put a previous org back in the LRU afterwards.
+func (s *orgServiceImpl) swapStaging(ctx context.Context) (err error) {
+	_, span := tracer.Start(ctx, "bolt.swapStaging", trace.WithAttributes(attribute.String("db.system", "boltdb")))
+	defer func() { endSpan(span, err) }()
+	if err := s.replaceCacheBucket(); err != nil {
 		return err
 	}
+	s.Lock()
+	defer s.Unlock()
+	s.orgCache.purge()
+	s.conflicts = s.stagedConflicts
+	s.validation = s.stagedValidation
+	return nil
+}
 
-	for {
-		log.Printf("Getting terms for responseCount %d", responseCount)
-		terms, err := s.repository.GetTmeTermsFromIndex(responseCount)
+func (s *orgServiceImpl) replaceCacheBucket() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		staging := tx.Bucket([]byte(stagingBucket))
+		if staging == nil {
+			return fmt.Errorf("Bucket %v not found!", stagingBucket)
+		}
+		if err := updateChangeTimes(tx, staging, time.Now()); err != nil {
+			return err
+		}
+		err := tx.DeleteBucket([]byte(cacheBucket))
+		if err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		bucket, err := tx.CreateBucket([]byte(cacheBucket))
 		if err != nil {
 			return err
 		}
-		if len(terms) < 1 {
-			log.Printf("Finished fetching organisations from TME. Waiting subroutines to terminate\n")
-			break
+		err = staging.ForEach(func(k, v []byte) error {
+			// bolt only guarantees k and v for the life of the transaction, copy them as staging is deleted below
+			return bucket.Put(append([]byte(nil), k...), append([]byte(nil), v...))
+		})
+		if err != nil {
+			return err
 		}
-		wg.Add(1)
-		go s.initOrgsMap(terms, s.db, &wg)
-		responseCount += s.maxTmeRecords
+		return tx.DeleteBucket([]byte(stagingBucket))
+	})
+}
+
+// updateChangeTimes records now as the change time of the staged orgs that are new or differ from the cached ones, and forgets the orgs no longer staged
+func updateChangeTimes(tx *bolt.Tx, staging *bolt.Bucket, now time.Time) error {
+	meta, err := tx.CreateBucketIfNotExists([]byte(metaBucket))
+	if err != nil {
+		return err
 	}
-	wg.Wait()
+	previous := tx.Bucket([]byte(cacheBucket))
+	changed := []byte(now.UTC().Format(time.RFC3339Nano))
+	err = staging.ForEach(func(k, v []byte) error {
+		if meta.Get(k) != nil && previous != nil && bytes.Equal(previous.Get(k), v) {
+			return nil
+		}
+		return meta.Put(append([]byte(nil), k...), changed)
+	})
+	if err != nil {
+		return err
+	}
+	var removed [][]byte
+	err = meta.ForEach(func(k, v []byte) error {
+		if staging.Get(k) == nil {
+			removed = append(removed, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range removed {
+		if err := meta.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *orgServiceImpl) discardStaging() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket([]byte(stagingBucket))
+		if err == bolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+}
+
+func (s *orgServiceImpl) init() error {
+	ctx, l, err := s.startLoad(context.Background())
+	if err != nil {
+		return err
+	}
+	return s.initFrom(ctx, l, 0, false)
+}
 
-	count, _ := s.orgCount()
+// initFrom loads orgs from the given offset. When resuming, the pages staged by the previous load are kept.
+func (s *orgServiceImpl) initFrom(ctx context.Context, l *loadTracker, offset int, resume bool) error {
+	defer s.finishLoad()
+	ctx, span := tracer.Start(ctx, "orgs.load", trace.WithAttributes(attribute.String("load.id", l.id), attribute.Int("load.offset", offset), attribute.Bool("load.resume", resume)))
+	complete, err := s.load(ctx, l, offset, resume)
+	status := l.finish(err, complete)
+	span.SetAttributes(attribute.String("load.outcome", status.Outcome))
+	endSpan(span, err)
+	loadDuration.WithLabelValues(status.Outcome).Observe(status.Finished.Sub(status.Started).Seconds())
+	s.setLastLoadStatus(status)
+	if len(status.FailedOffsets) > 0 && status.Outcome != loadCancelled {
+		s.setResumeOffset(status.FailedOffsets[0], true)
+	} else {
+		s.setResumeOffset(0, false)
+	}
+	if err != nil {
+		l.log.WithFields(log.Fields{"outcome": status.Outcome, "failedOffsets": status.FailedOffsets}).Error(status)
+		if s.hasPreviousData() {
+			l.log.Warn("Serving the previously loaded organisations")
+			s.setDataLoaded(true)
+			s.setInitialised(true)
+		}
+		return err
+	}
 	s.setDataLoaded(true)
 	s.setInitialised(true)
-	log.Printf("Added %d orgs UUIDs\n", count)
 	return nil
 }
 
-func (s *orgServiceImpl) getOrgs() ([]orgLink, error) {
+// load fetches every page from TME into the staging bucket and swaps it in. complete tells whether every page was fetched.
+func (s *orgServiceImpl) load(ctx context.Context, l *loadTracker, offset int, resume bool) (complete bool, err error) {
+	l.log.WithFields(log.Fields{"offset": offset, "resume": resume, "pipeline": s.pipeline.String()}).Info("Fetching organisations from TME")
+
+	err = s.openDB(resume)
+	if err != nil {
+		return false, err
+	}
+	if !resume {
+		s.Lock()
+		s.stagedConflicts = newConflictReport()
+		s.stagedValidation = s.validation.fresh()
+		s.Unlock()
+		// a resumed load carries on with the terms of the load it resumes
+		if err := refreshRepository(s.repository); err != nil {
+			return false, err
+		}
+	}
+
+	if err := s.runPipeline(ctx, l, offset); err != nil {
+		if errors.Is(err, context.Canceled) {
+			if err := s.discardStaging(); err != nil {
+				l.log.WithError(err).Error("Error discarding staged orgs")
+			}
+		}
+		return false, err
+	}
+
+	if failed := l.failures(); len(failed) > 0 {
+		return true, fmt.Errorf("Failed to fetch or store the organisations of %d pages", len(failed))
+	}
+	previous, loaded, err := s.stagedCounts()
+	if err != nil {
+		return true, err
+	}
+	if err := s.countGuard.check(previous, loaded); err != nil {
+		if err := s.discardStaging(); err != nil {
+			l.log.WithError(err).Error("Error discarding staged orgs")
+		}
+		return true, err
+	}
+	if err := s.swapStaging(ctx); err != nil {
+		return true, err
+	}
+
+	l.log.WithFields(log.Fields{"count": loaded, "previousCount": previous}).Info("Loaded organisations")
+	for _, r := range s.validationSummary().Rules {
+		if r.Count > 0 {
+			l.log.WithFields(log.Fields{"rule": r.Rule, "count": r.Count, "sampleUUIDs": r.SampleUUIDs}).Warn("Validation rule failed")
+		}
+	}
+	return true, nil
+}
+
+func (s *orgServiceImpl) hasPreviousData() bool {
+	if s.db == nil {
+		return false
+	}
+	count, err := s.orgCount(context.Background())
+	return err == nil && count > 0
+}
+
+// getOrgs links the cached orgs passing the filter in UUID order, decoding them only when a filter is set. The page is
+// taken from the orgs passing the filter, and when more of them follow it, the UUID to list the next page after is given.
+func (s *orgServiceImpl) getOrgs(ctx context.Context, filter orgFilter, page orgPage) ([]orgLink, string, error) {
 	s.RLock()
 	defer s.RUnlock()
 	var linkList []orgLink
-	err := s.db.View(func(tx *bolt.Tx) error {
+	var next string
+	err := tracedView(ctx, s.db, "getOrgs", func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(cacheBucket))
 		if bucket == nil {
 			return fmt.Errorf("Bucket %v not found!", cacheBucket)
 		}
-
-		bucket.ForEach(func(k, v []byte) error {
+		meta := tx.Bucket([]byte(metaBucket))
+
+		c := bucket.Cursor()
+		k, v := c.First()
+		if page.after != "" {
+			k, v = c.Seek([]byte(page.after))
+			if k != nil && string(k) == page.after {
+				k, v = c.Next()
+			}
+		}
+		var last []byte
+		for ; k != nil; k, v = c.Next() {
+			if !filter.isEmpty() {
+				var cachedOrg org
+				if err := json.Unmarshal(v, &cachedOrg); err != nil {
+					return fmt.Errorf("Could not unmarshal cached organisation [%s]: %v", k, err)
+				}
+				if !filter.matches(cachedOrg, changeTime(meta, k)) {
+					continue
+				}
+			}
+			if page.full(len(linkList)) {
+				next = string(last)
+				return nil
+			}
 			linkList = append(linkList, orgLink{APIURL: s.baseURL + string(k)})
-			return nil
-		})
+			last = k
+		}
 		return nil
 	})
 
-	return linkList, err
+	return linkList, next, err
+}
+
+// changeTime gives when an org last changed, or the zero time when that was not recorded or cannot be read
+func changeTime(meta *bolt.Bucket, k []byte) time.Time {
+	if meta == nil {
+		return time.Time{}
+	}
+	recorded := meta.Get(k)
+	if recorded == nil {
+		return time.Time{}
+	}
+	changed, err := time.Parse(time.RFC3339Nano, string(recorded))
+	if err != nil {
+		log.WithError(err).WithField("uuid", string(k)).Warn("Could not read when the organisation last changed")
+	}
+	return changed
 }
 
-func (s *orgServiceImpl) getOrgByUUID(uuid string) (org, bool, error) {
+func (s *orgServiceImpl) getOrgByUUID(ctx context.Context, uuid string) (org, bool, error) {
 	s.RLock()
 	defer s.RUnlock()
+	if cachedOrg, found := s.orgCache.get(uuid); found {
+		return cachedOrg, true, nil
+	}
 	var cachedValue []byte
-	err := s.db.View(func(tx *bolt.Tx) error {
+	err := tracedView(ctx, s.db, "getOrgByUUID", func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(cacheBucket))
 		if bucket == nil {
 			return fmt.Errorf("Bucket %v not found!", cacheBucket)
@@ -182,32 +542,44 @@ func (s *orgServiceImpl) getOrgByUUID(uuid string) (org, bool, error) {
 		log.Errorf("ERROR unmarshalling cached value for [%v]: %v", uuid, err.Error())
 		return org{}, true, err
 	}
+	s.orgCache.add(uuid, cachedOrg)
 	return cachedOrg, true, nil
 
 }
 
-func (s *orgServiceImpl) initOrgsMap(terms []interface{}, db *bolt.DB, wg *sync.WaitGroup) {
-	var cacheToBeWritten []org
-	for _, iTerm := range terms {
-		cacheToBeWritten = append(cacheToBeWritten, transformOrg(iTerm.(term), s.taxonomyName))
-	}
-
-	go storeOrgToCache(db, cacheToBeWritten, wg)
-}
-
-func storeOrgToCache(db *bolt.DB, cacheToBeWritten []org, wg *sync.WaitGroup) {
-	defer wg.Done()
-	err := db.Batch(func(tx *bolt.Tx) error {
-
-		bucket := tx.Bucket([]byte(cacheBucket))
+func (s *orgServiceImpl) storeOrgToCache(ctx context.Context, cacheToBeWritten []org, offset int, l *loadTracker) {
+	_, span := tracer.Start(ctx, "bolt.storePage", trace.WithAttributes(attribute.String("db.system", "boltdb"), attribute.Int("tme.offset", offset)))
+	var stored []org
+	var duplicates [][2]org
+	err := s.db.Batch(func(tx *bolt.Tx) error {
+		// Batch may call this function more than once, so only keep what the successful run found
+		stored, duplicates = nil, nil
+		bucket := tx.Bucket([]byte(stagingBucket))
 		if bucket == nil {
-			return fmt.Errorf("Cache bucket [%v] not found!", cacheBucket)
+			return fmt.Errorf("Cache bucket [%v] not found!", stagingBucket)
 		}
 		for _, anOrg := range cacheToBeWritten {
 			marshalledOrg, err := json.Marshal(anOrg)
 			if err != nil {
 				return err
 			}
+			if existing := bucket.Get([]byte(anOrg.UUID)); existing != nil {
+				// a resumed load fetches again pages already staged by the previous attempt
+				if bytes.Equal(existing, marshalledOrg) {
+					continue
+				}
+				var stagedOrg org
+				if err := json.Unmarshal(existing, &stagedOrg); err != nil {
+					return err
+				}
+				duplicates = append(duplicates, [2]org{stagedOrg, anOrg})
+				// pages are written in no set order, so keep whichever org wins over all the others
+				if !keptOver(anOrg, stagedOrg) {
+					continue
+				}
+			} else {
+				stored = append(stored, anOrg)
+			}
 			err = bucket.Put([]byte(anOrg.UUID), marshalledOrg)
 			if err != nil {
 				return err
@@ -215,17 +587,32 @@ func storeOrgToCache(db *bolt.DB, cacheToBeWritten []org, wg *sync.WaitGroup) {
 		}
 		return nil
 	})
+	endSpan(span, err)
+	pageLog := l.log.WithField("offset", offset)
 	if err != nil {
-		log.Errorf("ERROR storing to cache: %+v", err)
+		pageLog.WithError(err).Error("Error storing page to cache")
+		l.pageFailed(offset, err)
+		return
+	}
+	pageLog.WithFields(log.Fields{"count": len(cacheToBeWritten), "stored": len(stored), "duplicates": len(duplicates)}).Info("Stored page")
+	for _, anOrg := range stored {
+		s.stagedValidation.validate(anOrg)
+	}
+	for _, d := range duplicates {
+		// a resumed load fetches again pages whose orgs lost to an org already staged
+		if !s.stagedConflicts.add(d[0], d[1]) {
+			continue
+		}
+		pageLog.WithFields(log.Fields{"uuid": d[0].UUID, "stagedTME": d[0].AlternativeIdentifiers.TME, "otherTME": d[1].AlternativeIdentifiers.TME}).Warn("UUID collision")
+		s.stagedValidation.validate(d[1])
 	}
-
 }
 
 // HELPER METHODS
 
-func (s *orgServiceImpl) orgCount() (int, error) {
+func (s *orgServiceImpl) orgCount(ctx context.Context) (int, error) {
 	var count int
-	err := s.db.View(func(tx *bolt.Tx) error {
+	err := tracedView(ctx, s.db, "orgCount", func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(cacheBucket))
 		if bucket == nil {
 			return fmt.Errorf("Bucket %v not found!", cacheBucket)
@@ -238,11 +625,11 @@ func (s *orgServiceImpl) orgCount() (int, error) {
 	return count, err
 }
 
-func (s *orgServiceImpl) orgIds() ([]orgUUID, error) {
+func (s *orgServiceImpl) orgIds(ctx context.Context) ([]orgUUID, error) {
 	s.RLock()
 	defer s.RUnlock()
 	var uuidList []orgUUID
-	err := s.db.View(func(tx *bolt.Tx) error {
+	err := tracedView(ctx, s.db, "orgIds", func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(cacheBucket))
 		if bucket == nil {
 			return fmt.Errorf("Bucket %v not found!", cacheBucket)
@@ -258,12 +645,35 @@ func (s *orgServiceImpl) orgIds() ([]orgUUID, error) {
 	return uuidList, err
 }
 
-func (s *orgServiceImpl) orgReload() error {
-	s.setDataLoaded(false)
-	err := s.shutdown()
+func (s *orgServiceImpl) uuidConflicts() []uuidConflict {
+	s.RLock()
+	defer s.RUnlock()
+	return s.conflicts.list()
+}
+
+func (s *orgServiceImpl) validationSummary() validationSummary {
+	s.RLock()
+	defer s.RUnlock()
+	return s.validation.summary()
+}
+
+// orgReload starts a load in the background and returns its ID. ctx only needs to live as long as the load.
+func (s *orgServiceImpl) orgReload(ctx context.Context, resume bool) (string, error) {
+	offset := 0
+	if resume {
+		var ok bool
+		if offset, ok = s.resumeOffset(); !ok {
+			return "", errNothingToResume
+		}
+	}
+	ctx, l, err := s.startLoad(ctx)
 	if err != nil {
-		return err
+		return "", err
 	}
-
-	return s.init()
+	go func() {
+		if err := s.initFrom(ctx, l, offset, resume); err != nil {
+			l.log.WithError(err).Error("Reload failed")
+		}
+	}()
+	return l.id, nil
 }
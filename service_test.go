@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/boltdb/bolt"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -28,16 +32,16 @@ func TestGetOrganisations(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		runTestForOrgs(test, assert)
+		runTestForOrgs(t, test, assert)
 	}
 }
 
-func runTestForOrgs(test testSuiteForOrgs, assert *assert.Assertions) {
+func runTestForOrgs(t *testing.T, test testSuiteForOrgs, assert *assert.Assertions) {
 	repo := dummyRepo{terms: test.terms, err: test.err}
-	service := newOrgService(&repo, test.baseURL, "ON", 10000, "test1.db")
-	defer service.shutdown()
-	time.Sleep(3 * time.Second) //waiting initialization to be finished
-	actualOrgansiations, _ := service.getOrgs()
+	service := newOrgService(&repo, test.baseURL, "ON", 10000, testCacheFile(t), 10, defaultValidation(), countDropGuard{}, pageRetryPolicy{}, pipelineConfig{})
+	defer service.shutdown(context.Background())
+	waitForLoad(t, service)
+	actualOrgansiations, _, _ := service.getOrgs(context.Background(), orgFilter{}, orgPage{})
 	assert.Equal(test.orgs, actualOrgansiations, fmt.Sprintf("%s: Expected organsiations link incorrect", test.name))
 }
 
@@ -61,16 +65,16 @@ func TestGetOrganisationByUuid(t *testing.T) {
 		{"Error on init", []term{}, "some uuid", org{}, false, nil},
 	}
 	for _, test := range tests {
-		runTestForOrgByUUID(test, assert)
+		runTestForOrgByUUID(t, test, assert)
 	}
 }
 
-func runTestForOrgByUUID(test testSuiteForOrg, assert *assert.Assertions) {
+func runTestForOrgByUUID(t *testing.T, test testSuiteForOrg, assert *assert.Assertions) {
 	repo := dummyRepo{terms: test.terms, err: test.err}
-	service := newOrgService(&repo, "", "ON", 10000, "test2.db")
-	defer service.shutdown()
-	time.Sleep(3 * time.Second) //waiting initialization to be finished
-	actualOrganisation, found, err := service.getOrgByUUID(test.uuid)
+	service := newOrgService(&repo, "", "ON", 10000, testCacheFile(t), 10, defaultValidation(), countDropGuard{}, pageRetryPolicy{}, pipelineConfig{})
+	defer service.shutdown(context.Background())
+	waitForLoad(t, service)
+	actualOrganisation, found, err := service.getOrgByUUID(context.Background(), test.uuid)
 	assert.Equal(test.org, actualOrganisation, fmt.Sprintf("%s: Expected organsiation incorrect", test.name))
 	assert.Equal(test.found, found)
 	assert.Equal(test.err, err)
@@ -109,16 +113,458 @@ func TestOrgIDs(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		runTestForOrgID(test, assert)
+		runTestForOrgID(t, test, assert)
 	}
 }
 
-func runTestForOrgID(test testSuiteForOrgID, assert *assert.Assertions) {
+func runTestForOrgID(t *testing.T, test testSuiteForOrgID, assert *assert.Assertions) {
 	repo := dummyRepo{terms: test.terms, err: test.err}
-	service := newOrgService(&repo, "", "ON", 10000, "test3.db")
-	defer service.shutdown()
-	time.Sleep(3 * time.Second) //waiting initialization to be finished
-	actualIDs, err := service.orgIds()
+	service := newOrgService(&repo, "", "ON", 10000, testCacheFile(t), 10, defaultValidation(), countDropGuard{}, pageRetryPolicy{}, pipelineConfig{})
+	defer service.shutdown(context.Background())
+	waitForLoad(t, service)
+	actualIDs, err := service.orgIds(context.Background())
 	assert.Equal(test.orgUUIDs, actualIDs, fmt.Sprintf("%s: Expected orgIDs incorrect", test.name))
 	assert.Equal(test.err, err)
 }
+
+func TestUUIDConflicts(t *testing.T) {
+	assert := assert.New(t)
+	repo := dummyRepo{terms: []term{
+		term{CanonicalName: "European Union", RawID: "Nstein_GL_US_NY_Municipality_942968"},
+		term{CanonicalName: "EU", RawID: "Nstein_GL_US_NY_Municipality_942968"},
+	}}
+	service := newOrgService(&repo, "", "ON", 10000, testCacheFile(t), 10, defaultValidation(), countDropGuard{}, pageRetryPolicy{}, pipelineConfig{})
+	defer service.shutdown(context.Background())
+	waitForLoad(t, service)
+
+	conflicts := service.uuidConflicts()
+	assert.Len(conflicts, 1, "Expected one UUID conflict")
+	assert.Equal("6a7edb42-c27a-3186-a0b9-7e3cdc91e16b", conflicts[0].UUID)
+	assert.Equal("EU", conflicts[0].Orgs[0].PrefLabel, "First org should be the one kept")
+	assert.Equal("European Union", conflicts[0].Orgs[1].PrefLabel)
+
+	kept, found, _ := service.getOrgByUUID(context.Background(), "6a7edb42-c27a-3186-a0b9-7e3cdc91e16b")
+	assert.True(found)
+	assert.Equal("EU", kept.PrefLabel, "Org with the lowest encoding should be kept whatever came first")
+}
+
+func TestCountDropAbortsLoad(t *testing.T) {
+	assert := assert.New(t)
+	repo := dummyRepo{terms: []term{
+		term{CanonicalName: "European Union", RawID: "Nstein_GL_US_NY_Municipality_942968"},
+		term{CanonicalName: "European Economic Community", RawID: "Nstein_GL_US_NY_Municipality_942969"},
+	}}
+	service := newOrgService(&repo, "", "ON", 10000, testCacheFile(t), 10, defaultValidation(), countDropGuard{maxDropPercent: 10}, pageRetryPolicy{}, pipelineConfig{})
+	defer service.shutdown(context.Background())
+	waitForLoad(t, service)
+	status, _ := service.lastLoadStatus()
+	assert.Equal(loadSucceeded, status.Outcome)
+
+	repo.terms = repo.terms[:1]
+	err := reloadAndWait(service, false)
+	assert.Error(err, "Reload dropping 50% of orgs should be aborted")
+	status, _ = service.lastLoadStatus()
+	assert.Equal(loadFailed, status.Outcome)
+	assert.Equal(err.Error(), status.Error)
+	count, _ := service.orgCount(context.Background())
+	assert.Equal(2, count, "Previous orgs should be kept")
+	assert.True(service.isInitialised())
+	assert.True(service.isDataLoaded())
+
+	repo.terms = append(repo.terms, term{CanonicalName: "European Economic Community", RawID: "Nstein_GL_US_NY_Municipality_942969"})
+	assert.NoError(reloadAndWait(service, false))
+	status, _ = service.lastLoadStatus()
+	assert.Equal(loadSucceeded, status.Outcome, "A successful load should replace the failed status")
+}
+
+type testSuiteForLoadStatus struct {
+	name          string
+	repo          dummyRepo
+	outcome       string
+	failedOffsets []int
+	initialised   bool
+}
+
+func TestLoadStatus(t *testing.T) {
+	assert := assert.New(t)
+	tests := []testSuiteForLoadStatus{
+		{"Success", dummyRepo{terms: []term{term{CanonicalName: "European Union", RawID: "Nstein_GL_US_NY_Municipality_942968"}}}, loadSucceeded, []int{}, true},
+		{"Error fetching first page", dummyRepo{err: errors.New("Error getting taxonomy")}, loadFailed, []int{0}, false},
+	}
+
+	for _, test := range tests {
+		service := newOrgService(&test.repo, "", "ON", 10000, testCacheFile(t), 10, defaultValidation(), countDropGuard{}, pageRetryPolicy{}, pipelineConfig{})
+		waitForLoad(t, service)
+		status, found := service.lastLoadStatus()
+		assert.True(found, fmt.Sprintf("%s: Load status missing", test.name))
+		assert.Equal(test.outcome, status.Outcome, fmt.Sprintf("%s: Wrong outcome", test.name))
+		assert.Equal(test.failedOffsets, status.FailedOffsets, fmt.Sprintf("%s: Wrong failed offsets", test.name))
+		assert.Equal(test.initialised, service.isInitialised(), fmt.Sprintf("%s: Wrong initialised state", test.name))
+		assert.Equal(test.initialised, service.isDataLoaded(), fmt.Sprintf("%s: Wrong data loaded state", test.name))
+		service.shutdown(context.Background())
+	}
+}
+
+type flakyRepo struct {
+	terms    map[int][]term
+	failures map[int]int
+	calls    map[int]int
+}
+
+func (r *flakyRepo) GetTmeTermsFromIndex(startRecord int) ([]interface{}, error) {
+	r.calls[startRecord]++
+	if r.failures[startRecord] > 0 {
+		r.failures[startRecord]--
+		return nil, fmt.Errorf("Error getting page %d", startRecord)
+	}
+	var interfaces []interface{}
+	for _, t := range r.terms[startRecord] {
+		interfaces = append(interfaces, t)
+	}
+	return interfaces, nil
+}
+
+func (r *flakyRepo) GetTmeTermById(uuid string) (interface{}, error) {
+	return nil, nil
+}
+
+func TestFailedPagesAreRetriedAndResumed(t *testing.T) {
+	assert := assert.New(t)
+	repo := flakyRepo{
+		terms: map[int][]term{
+			0:  []term{term{CanonicalName: "European Union", RawID: "Nstein_GL_US_NY_Municipality_942968"}},
+			10: []term{term{CanonicalName: "European Economic Community", RawID: "Nstein_GL_US_NY_Municipality_942969"}},
+		},
+		failures: map[int]int{0: 1, 10: 5},
+		calls:    map[int]int{},
+	}
+	service := newOrgService(&repo, "", "ON", 10, testCacheFile(t), 10, defaultValidation(), countDropGuard{}, pageRetryPolicy{retries: 2, maxConsecutiveFailures: 2}, pipelineConfig{})
+	defer service.shutdown(context.Background())
+	waitForLoad(t, service)
+
+	status, _ := service.lastLoadStatus()
+	assert.Equal(loadPartial, status.Outcome, "Page 10 should have failed after its retries")
+	assert.Equal([]int{10}, status.FailedOffsets)
+	assert.Equal(2, repo.calls[0], "Page 0 should have succeeded on its first retry")
+	assert.Equal(3, repo.calls[10], "Page 10 should have been tried once and retried twice")
+	assert.False(service.isInitialised(), "Partial load should not be served")
+	offset, ok := service.resumeOffset()
+	assert.True(ok)
+	assert.Equal(10, offset)
+
+	assert.NoError(reloadAndWait(service, true))
+	status, _ = service.lastLoadStatus()
+	assert.Equal(loadSucceeded, status.Outcome)
+	assert.Equal(2, repo.calls[0], "Page 0 should not be fetched again on resume")
+	count, _ := service.orgCount(context.Background())
+	assert.Equal(2, count, "Orgs staged before the resume should be kept")
+	assert.Empty(service.uuidConflicts(), "Pages fetched again should not be reported as conflicts")
+	_, ok = service.resumeOffset()
+	assert.False(ok, "Nothing left to resume")
+}
+
+func TestLoadAbortsAfterConsecutivePageFailures(t *testing.T) {
+	assert := assert.New(t)
+	repo := flakyRepo{
+		terms:    map[int][]term{0: []term{term{CanonicalName: "European Union", RawID: "Nstein_GL_US_NY_Municipality_942968"}}},
+		failures: map[int]int{10: 1, 20: 1},
+		calls:    map[int]int{},
+	}
+	service := newOrgService(&repo, "", "ON", 10, testCacheFile(t), 10, defaultValidation(), countDropGuard{}, pageRetryPolicy{maxConsecutiveFailures: 2}, pipelineConfig{})
+	defer service.shutdown(context.Background())
+	waitForLoad(t, service)
+
+	status, _ := service.lastLoadStatus()
+	assert.Equal(loadFailed, status.Outcome)
+	assert.Equal([]int{10, 20}, status.FailedOffsets)
+	assert.Equal(0, repo.calls[30], "Load should stop fetching once aborted")
+}
+
+// testCacheFile gives each test a cache file of its own, removed once the test is done
+func testCacheFile(t testing.TB) string {
+	return filepath.Join(t.TempDir(), "cache.db")
+}
+
+// eventually polls cond until it holds, failing the test after 10 seconds
+func eventually(t testing.TB, cond func() bool, msg string) {
+	t.Helper()
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("Timed out: %s", msg)
+}
+
+func loadRunning(service orgsService) bool {
+	s := service.(*orgServiceImpl)
+	s.RLock()
+	defer s.RUnlock()
+	return s.running != nil
+}
+
+// waitForLoad waits for the load a new service starts with to finish, whatever its outcome
+func waitForLoad(t testing.TB, service orgsService) {
+	t.Helper()
+	eventually(t, func() bool {
+		_, finished := service.lastLoadStatus()
+		return finished && !loadRunning(service)
+	}, "The first load should finish")
+}
+
+// reloadAndWait runs a reload to completion, returning the error it finished with
+func reloadAndWait(service orgsService, resume bool) error {
+	if _, err := service.orgReload(context.Background(), resume); err != nil {
+		return err
+	}
+	service.(*orgServiceImpl).loads.Wait()
+	if status, _ := service.lastLoadStatus(); status.Error != "" {
+		return errors.New(status.Error)
+	}
+	return nil
+}
+
+// endlessRepo returns one org per page, up to limit pages (no limit when 0)
+type endlessRepo struct {
+	delay time.Duration
+	limit int
+}
+
+func (r *endlessRepo) GetTmeTermsFromIndex(startRecord int) ([]interface{}, error) {
+	time.Sleep(r.delay)
+	if r.limit > 0 && startRecord >= r.limit {
+		return nil, nil
+	}
+	return []interface{}{term{CanonicalName: "Org", RawID: fmt.Sprintf("org-%d", startRecord)}}, nil
+}
+
+func (r *endlessRepo) GetTmeTermById(uuid string) (interface{}, error) {
+	return nil, nil
+}
+
+func TestShutdownCancelsRunningLoad(t *testing.T) {
+	assert := assert.New(t)
+	service := newOrgService(&endlessRepo{delay: 10 * time.Millisecond}, "", "ON", 1, testCacheFile(t), 10, defaultValidation(), countDropGuard{}, pageRetryPolicy{}, pipelineConfig{})
+	eventually(t, func() bool { return loadRunning(service) }, "The first load should start")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(service.shutdown(ctx), "Shutdown should stop the load in time")
+
+	status, ok := service.lastLoadStatus()
+	assert.True(ok, "Cancelled load should have a status")
+	assert.Equal(loadCancelled, status.Outcome)
+	assert.Contains(status.Error, "Load cancelled")
+	assert.False(service.isInitialised(), "Cancelled first load should not initialise the service")
+}
+
+func TestCancelReloadKeepsPreviousData(t *testing.T) {
+	assert := assert.New(t)
+	repo := &endlessRepo{delay: 10 * time.Millisecond, limit: 2}
+	service := newOrgService(repo, "", "ON", 1, testCacheFile(t), 10, defaultValidation(), countDropGuard{}, pageRetryPolicy{}, pipelineConfig{})
+	defer service.shutdown(context.Background())
+	waitForLoad(t, service)
+	count, _ := service.orgCount(context.Background())
+	assert.Equal(2, count)
+
+	repo.limit = 0
+	id, err := service.orgReload(context.Background(), false)
+	assert.NoError(err)
+	_, err = service.orgReload(context.Background(), false)
+	assert.True(errors.Is(err, errLoadRunning), "Only one load should run at a time")
+	eventually(t, func() bool {
+		_, loaded, _ := service.(*orgServiceImpl).stagedCounts()
+		return loaded > 0
+	}, "The reload should stage some orgs")
+	assert.False(service.cancelLoad("unknown"), "Only the running load can be cancelled")
+	assert.True(service.cancelLoad(id))
+	service.(*orgServiceImpl).loads.Wait()
+
+	status, _ := service.lastLoadStatus()
+	assert.Equal(id, status.ID)
+	assert.Equal(loadCancelled, status.Outcome)
+	count, _ = service.orgCount(context.Background())
+	assert.Equal(2, count, "Previous orgs should be kept")
+	_, loaded, _ := service.(*orgServiceImpl).stagedCounts()
+	assert.Equal(0, loaded, "Staged orgs should be discarded")
+	_, ok := service.resumeOffset()
+	assert.False(ok, "A cancelled load cannot be resumed")
+	assert.False(service.cancelLoad(id), "A finished load cannot be cancelled")
+}
+
+func TestGetOrganisationsFiltered(t *testing.T) {
+	assert := assert.New(t)
+	repo := &dummyRepo{terms: []term{
+		term{CanonicalName: "European Union", RawID: "Nstein_GL_US_NY_Municipality_942968", Aliases: aliases{Alias: []alias{alias{Name: "EU"}}}},
+		term{CanonicalName: "European Economic Community", RawID: "Nstein_GL_US_NY_Municipality_942969"},
+	}}
+	service := newOrgService(repo, "", "ON", 10000, testCacheFile(t), 10, defaultValidation(), countDropGuard{}, pageRetryPolicy{}, pipelineConfig{})
+	defer service.shutdown(context.Background())
+	waitForLoad(t, service)
+
+	const eu = "6a7edb42-c27a-3186-a0b9-7e3cdc91e16b"
+	euIdentifier := buildTmeIdentifier("Nstein_GL_US_NY_Municipality_942968", "ON")
+	links, _, err := service.getOrgs(context.Background(), orgFilter{alias: "eu"}, orgPage{})
+	assert.NoError(err)
+	assert.Equal([]orgLink{orgLink{APIURL: eu}}, links, "Only the org with the alias should be listed")
+	links, _, _ = service.getOrgs(context.Background(), orgFilter{tmeIDPrefix: euIdentifier}, orgPage{})
+	assert.Equal([]orgLink{orgLink{APIURL: eu}}, links, "Only the org with the TME ID should be listed")
+	links, _, _ = service.getOrgs(context.Background(), orgFilter{orgType: "Organisation"}, orgPage{})
+	assert.Len(links, 2)
+	links, _, _ = service.getOrgs(context.Background(), orgFilter{orgType: "Company"}, orgPage{})
+	assert.Empty(links)
+
+	beforeReload := time.Now()
+	repo.terms[1].CanonicalName = "EEC"
+	assert.NoError(reloadAndWait(service, false))
+	links, _, _ = service.getOrgs(context.Background(), orgFilter{changedSince: beforeReload}, orgPage{})
+	assert.Len(links, 1, "Only the org changed by the reload should be listed")
+	assert.NotEqual(eu, links[0].APIURL)
+	links, _, _ = service.getOrgs(context.Background(), orgFilter{changedSince: beforeReload.Add(-time.Hour)}, orgPage{})
+	assert.Len(links, 2, "Every org changed within the hour should be listed")
+}
+
+func TestGetOrgsPages(t *testing.T) {
+	assert := assert.New(t)
+	var terms []term
+	for i := 0; i < 7; i++ {
+		t := term{CanonicalName: fmt.Sprintf("Org %d", i), RawID: fmt.Sprintf("org-%d", i)}
+		if i%2 == 0 {
+			t.Aliases = aliases{Alias: []alias{alias{Name: "Even"}}}
+		}
+		terms = append(terms, t)
+	}
+	service := newOrgService(&dummyRepo{terms: terms}, "", "ON", 10000, testCacheFile(t), 10, defaultValidation(), countDropGuard{}, pageRetryPolicy{}, pipelineConfig{})
+	defer service.shutdown(context.Background())
+	waitForLoad(t, service)
+	all, _, _ := service.getOrgs(context.Background(), orgFilter{}, orgPage{})
+	even, _, _ := service.getOrgs(context.Background(), orgFilter{alias: "even"}, orgPage{})
+	assert.Len(even, 4)
+
+	tests := []struct {
+		name   string
+		filter orgFilter
+		links  []orgLink
+	}{
+		{"Every org", orgFilter{}, all},
+		{"Filtered orgs", orgFilter{alias: "even"}, even},
+	}
+	for _, test := range tests {
+		var paged []orgLink
+		page := orgPage{limit: 3}
+		for pages := 0; pages < 5; pages++ {
+			links, next, err := service.getOrgs(context.Background(), test.filter, page)
+			assert.NoError(err, test.name)
+			assert.True(len(links) <= 3, fmt.Sprintf("%s: Page over the limit", test.name))
+			paged = append(paged, links...)
+			if next == "" {
+				break
+			}
+			assert.Equal(next, links[len(links)-1].APIURL, fmt.Sprintf("%s: Next page should follow the last org listed", test.name))
+			page.after = next
+		}
+		assert.Equal(test.links, paged, fmt.Sprintf("%s: Pages should list each org once in UUID order", test.name))
+	}
+}
+
+func TestReloadReplacesCachedOrgs(t *testing.T) {
+	assert := assert.New(t)
+	repo := &dummyRepo{terms: []term{term{CanonicalName: "European Union", RawID: "Nstein_GL_US_NY_Municipality_942968"}}}
+	service := newOrgService(repo, "", "ON", 10000, testCacheFile(t), 10, defaultValidation(), countDropGuard{}, pageRetryPolicy{}, pipelineConfig{})
+	defer service.shutdown(context.Background())
+	waitForLoad(t, service)
+
+	const eu = "6a7edb42-c27a-3186-a0b9-7e3cdc91e16b"
+	loaded, _, _ := service.getOrgByUUID(context.Background(), eu)
+	assert.Equal("European Union", loaded.PrefLabel)
+	_, cached := service.(*orgServiceImpl).orgCache.get(eu)
+	assert.True(cached, "Org read should be kept in the LRU")
+
+	repo.terms[0].CanonicalName = "EU"
+	assert.NoError(reloadAndWait(service, false))
+	reloaded, found, err := service.getOrgByUUID(context.Background(), eu)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal("EU", reloaded.PrefLabel, "Reloaded org should be served, not the one cached before")
+}
+
+func TestSwapStagingDoesNotHoldReadsDuringTheCopy(t *testing.T) {
+	assert := assert.New(t)
+	repo := &dummyRepo{terms: []term{term{CanonicalName: "European Union", RawID: "Nstein_GL_US_NY_Municipality_942968"}}}
+	service := newOrgService(repo, "", "ON", 10000, testCacheFile(t), 10, defaultValidation(), countDropGuard{}, pageRetryPolicy{}, pipelineConfig{})
+	defer service.shutdown(context.Background())
+	waitForLoad(t, service)
+	s := service.(*orgServiceImpl)
+	assert.NoError(s.openDB(false))
+	assert.NoError(s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(stagingBucket)).Put([]byte(testUUID), []byte(`{"uuid":"`+testUUID+`"}`))
+	}))
+
+	// a read in progress holds the read lock, and the staged orgs should still be swapped in meanwhile
+	s.RLock()
+	var unlock sync.Once
+	defer unlock.Do(s.RUnlock)
+	swapped := make(chan error, 1)
+	go func() {
+		swapped <- s.swapStaging(context.Background())
+	}()
+	eventually(t, func() bool {
+		var found bool
+		s.db.View(func(tx *bolt.Tx) error {
+			found = tx.Bucket([]byte(cacheBucket)).Get([]byte(testUUID)) != nil
+			return nil
+		})
+		return found
+	}, "Staged orgs should be swapped in while a read is in progress")
+	unlock.Do(s.RUnlock)
+	assert.NoError(<-swapped)
+}
+
+func TestGetOrganisationByUuidServedFromLRU(t *testing.T) {
+	assert := assert.New(t)
+	repo := &dummyRepo{terms: []term{term{CanonicalName: "European Union", RawID: "Nstein_GL_US_NY_Municipality_942968"}}}
+	service := newOrgService(repo, "", "ON", 10000, testCacheFile(t), 10, defaultValidation(), countDropGuard{}, pageRetryPolicy{}, pipelineConfig{})
+	defer service.shutdown(context.Background())
+	waitForLoad(t, service)
+
+	const eu = "6a7edb42-c27a-3186-a0b9-7e3cdc91e16b"
+	s := service.(*orgServiceImpl)
+	_, found, _ := service.getOrgByUUID(context.Background(), eu)
+	assert.True(found)
+	assert.Equal(1, s.orgCache.len())
+
+	// once in the LRU, the org no longer needs bolt
+	assert.NoError(s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(cacheBucket)).Delete([]byte(eu))
+	}))
+	cached, found, err := service.getOrgByUUID(context.Background(), eu)
+	assert.NoError(err)
+	assert.True(found, "Org should be served from the LRU")
+	assert.Equal("European Union", cached.PrefLabel)
+
+	assert.NoError(reloadAndWait(service, false))
+	assert.Equal(0, s.orgCache.len(), "Reload should empty the LRU")
+}
+
+func TestReportsDescribeTheServedOrgs(t *testing.T) {
+	assert := assert.New(t)
+	eu := term{CanonicalName: "European Union", RawID: "Nstein_GL_US_NY_Municipality_942968"}
+	eec := term{CanonicalName: "European Economic Community", RawID: "Nstein_GL_US_NY_Municipality_942969"}
+	repo := &dummyRepo{terms: []term{eu, eec}}
+	service := newOrgService(repo, "", "ON", 10000, testCacheFile(t), 10, defaultValidation(), countDropGuard{maxDropPercent: 10}, pageRetryPolicy{}, pipelineConfig{})
+	defer service.shutdown(context.Background())
+	waitForLoad(t, service)
+	assert.Empty(service.uuidConflicts())
+
+	assert.Equal(2, service.validationSummary().Checked)
+
+	repo.terms = []term{eu, term{CanonicalName: "EU", RawID: eu.RawID}}
+	assert.Error(reloadAndWait(service, false), "Reload dropping an org should be aborted")
+	assert.Empty(service.uuidConflicts(), "Conflicts of a discarded load should not be reported")
+	assert.Equal(2, service.validationSummary().Checked, "Validation of a discarded load should not be reported")
+
+	repo.terms = append(repo.terms, eec)
+	assert.NoError(reloadAndWait(service, false))
+	assert.Len(service.uuidConflicts(), 1, "Conflicts of the served load should be reported")
+	assert.Equal(3, service.validationSummary().Checked, "Validation of the served load should be reported")
+}
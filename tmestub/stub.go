@@ -0,0 +1,107 @@
+// Package tmestub serves taxonomy pages from fixture files the way TME does, so the transformer can be run and tested without TME credentials.
+package tmestub
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const defaultMaximumRecords = 10
+
+type taxonomy struct {
+	Terms []rawTerm `xml:"term"`
+}
+
+type rawTerm struct {
+	Inner []byte `xml:",innerxml"`
+}
+
+// Stub is an http.Handler answering TME term queries from the terms of its fixtures, paged by the startRecord and maximumRecords parameters used by tmereader
+type Stub struct {
+	terms    [][]byte
+	username string
+	password string
+}
+
+// New loads the terms of the given taxonomy XML files, in order
+func New(fixtures ...string) (*Stub, error) {
+	s := &Stub{}
+	for _, f := range fixtures {
+		contents, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		t := taxonomy{}
+		if err := xml.Unmarshal(contents, &t); err != nil {
+			return nil, fmt.Errorf("Could not parse fixture [%v]: %v", f, err)
+		}
+		for _, term := range t.Terms {
+			s.terms = append(s.terms, term.Inner)
+		}
+	}
+	return s, nil
+}
+
+// WithBasicAuth makes the stub reject requests not carrying these credentials
+func (s *Stub) WithBasicAuth(username string, password string) *Stub {
+	s.username = username
+	s.password = password
+	return s
+}
+
+// Count gives the number of terms served
+func (s *Stub) Count() int {
+	return len(s.terms)
+}
+
+func (s *Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" || !strings.HasPrefix(r.URL.Path, "/rs/") || !strings.HasSuffix(r.URL.Path, "/terms") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if s.username != "" {
+		if u, p, ok := r.BasicAuth(); !ok || u != s.username || p != s.password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+	start, err := intParam(r, "startRecord", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	max, err := intParam(r, "maximumRecords", defaultMaximumRecords)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var page bytes.Buffer
+	page.WriteString("<taxonomy>")
+	for i := start; i < start+max && i < len(s.terms); i++ {
+		page.WriteString("<term>")
+		page.Write(s.terms[i])
+		page.WriteString("</term>")
+	}
+	page.WriteString("</taxonomy>")
+
+	w.Header().Set("Content-Type", "application/xml;charset=utf-8")
+	w.Write(page.Bytes())
+}
+
+func intParam(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("Invalid %v [%v]", name, v)
+	}
+	return i, nil
+}
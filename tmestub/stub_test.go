@@ -0,0 +1,56 @@
+package tmestub
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaging(t *testing.T) {
+	assert := assert.New(t)
+	stub, err := New("fixtures/organisations.xml")
+	assert.NoError(err)
+	assert.Equal(5, stub.Count())
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+		terms  int
+	}{
+		{"First page", "/rs/authorityfiles/GL/terms?startRecord=0&maximumRecords=2", http.StatusOK, 2},
+		{"Last page", "/rs/authorityfiles/GL/terms?startRecord=4&maximumRecords=2", http.StatusOK, 1},
+		{"Past the end", "/rs/authorityfiles/GL/terms?startRecord=6&maximumRecords=2", http.StatusOK, 0},
+		{"Default page size", "/rs/authorityfiles/GL/terms", http.StatusOK, 5},
+		{"Invalid offset", "/rs/authorityfiles/GL/terms?startRecord=x", http.StatusBadRequest, 0},
+		{"Unknown path", "/rs/authorityfiles/GL", http.StatusNotFound, 0},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		stub.ServeHTTP(rec, httptest.NewRequest("GET", test.url, nil))
+		assert.Equal(test.status, rec.Code, fmt.Sprintf("%s: Wrong response code", test.name))
+		assert.Equal(test.terms, strings.Count(rec.Body.String(), "<term>"), fmt.Sprintf("%s: Wrong number of terms", test.name))
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	assert := assert.New(t)
+	stub, err := New("fixtures/organisations.xml")
+	assert.NoError(err)
+	stub.WithBasicAuth("user", "pass")
+
+	rec := httptest.NewRecorder()
+	stub.ServeHTTP(rec, httptest.NewRequest("GET", "/rs/authorityfiles/GL/terms", nil))
+	assert.Equal(http.StatusUnauthorized, rec.Code)
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/rs/authorityfiles/GL/terms", nil)
+	req.SetBasicAuth("user", "pass")
+	stub.ServeHTTP(rec, req)
+	assert.Equal(http.StatusOK, rec.Code)
+}
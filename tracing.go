@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const transactionIDAttribute = "ft.transaction_id"
+
+var tracer = otel.Tracer("github.com/Financial-Times/v1-orgs-transformer")
+
+// setupTracing installs the global tracer provider, exporting spans over OTLP/gRPC when an endpoint is given.
+// Without an endpoint spans are not recorded. The returned function flushes and stops the exporter.
+func setupTracing(endpoint string, insecure bool, sampleRatio float64) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "v1-orgs-transformer"))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// tracingHandler starts a span per request named after the router route it matches, continuing any incoming trace
+func tracingHandler(router *mux.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(router, r)
+		tid := transactionID(w, r)
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String(transactionIDAttribute, tid),
+			))
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(transactionidutils.TransactionAwareContext(ctx, tid)))
+		span.SetAttributes(attribute.Int("http.response.status_code", rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+	})
+}
+
+// endSpan records err on the span before ending it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracedView runs a read-only bolt transaction inside a span
+func tracedView(ctx context.Context, db *bolt.DB, name string, fn func(tx *bolt.Tx) error) error {
+	_, span := tracer.Start(ctx, "bolt."+name, trace.WithAttributes(attribute.String("db.system", "boltdb")))
+	err := db.View(fn)
+	endSpan(span, err)
+	return err
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingHandler(t *testing.T) {
+	assert := assert.New(t)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	defer provider.Shutdown(context.Background())
+	// the global provider only delegates to the first one set, so point the tracer at this test's provider
+	previous := tracer
+	tracer = provider.Tracer("github.com/Financial-Times/v1-orgs-transformer")
+	defer func() { tracer = previous }()
+	_, err := setupTracing("", false, 1)
+	assert.NoError(err)
+
+	r := router(&dummyService{initialised: true, orgs: []org{org{UUID: testUUID}}})
+	req := newRequest("GET", fmt.Sprintf("/transformers/organisations/%s", testUUID))
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("X-Request-Id", "tid_test")
+	tracingHandler(r, r).ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if assert.Len(spans, 1) {
+		span := spans[0]
+		assert.Equal("GET /transformers/organisations/{uuid}", span.Name())
+		assert.Equal("4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String(), "Incoming trace should be continued")
+		assert.Contains(span.Attributes(), attribute.String(transactionIDAttribute, "tid_test"))
+	}
+}
+
+func TestLoadSpans(t *testing.T) {
+	assert := assert.New(t)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer provider.Shutdown(context.Background())
+	previous := tracer
+	tracer = provider.Tracer("github.com/Financial-Times/v1-orgs-transformer")
+	defer func() { tracer = previous }()
+
+	s := newPipelineTestService(&pagedRepo{total: 15, pageSize: 10}, testCacheFile(t), pipelineConfig{})
+	assert.NoError(s.init())
+	defer s.shutdown(context.Background())
+
+	var load sdktrace.ReadOnlySpan
+	children := map[string]int{}
+	for _, span := range recorder.Ended() {
+		if span.Name() == "orgs.load" {
+			load = span
+		}
+	}
+	if !assert.NotNil(load, "Load should be traced") {
+		return
+	}
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() == load.SpanContext().SpanID() {
+			children[span.Name()]++
+		}
+	}
+	assert.Equal(3, children["tme.fetchPage"], "Each page fetched, the empty one past the end included, should be traced under the load")
+	assert.Equal(2, children["bolt.storePage"], "Each page stored should be traced under the load")
+	assert.Equal(1, children["bolt.swapStaging"], "Swap should be traced under the load")
+}
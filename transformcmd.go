@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/jawher/mow.cli"
+	log "github.com/sirupsen/logrus"
+)
+
+// transformCommand turns taxonomy XML from files or stdin into NDJSON orgs on stdout, without running the server
+func transformCommand(cmd *cli.Cmd) {
+	taxonomyName := cmd.String(cli.StringOpt{
+		Name:  "taxonomy",
+		Value: "ON",
+		Desc:  "TME taxonomy name the terms belong to, used to build their identifiers and UUIDs",
+	})
+	files := cmd.Strings(cli.StringsArg{
+		Name: "FILE",
+		Desc: "Taxonomy XML files to transform, stdin if none",
+	})
+	cmd.Spec = "[--taxonomy] [FILE...]"
+
+	cmd.Action = func() {
+		if len(*files) == 0 {
+			if err := transformTaxonomy(os.Stdin, os.Stdout, *taxonomyName); err != nil {
+				log.Fatalf("Error transforming stdin: %v", err)
+			}
+			return
+		}
+		for _, f := range *files {
+			r, err := os.Open(f)
+			if err != nil {
+				log.Fatalf("Error opening [%v]: %v", f, err)
+			}
+			err = transformTaxonomy(r, os.Stdout, *taxonomyName)
+			r.Close()
+			if err != nil {
+				log.Fatalf("Error transforming [%v]: %v", f, err)
+			}
+		}
+	}
+}
+
+func transformTaxonomy(r io.Reader, w io.Writer, taxonomyName string) error {
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	terms, err := new(orgTransformer).UnMarshallTaxonomy(contents)
+	if err != nil {
+		return fmt.Errorf("Could not parse taxonomy: %v", err)
+	}
+	enc := json.NewEncoder(w)
+	for _, t := range terms {
+		if err := enc.Encode(transformOrg(t.(term), taxonomyName)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
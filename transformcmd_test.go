@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformTaxonomy(t *testing.T) {
+	assert := assert.New(t)
+	in := `<taxonomy><term><name>European Union</name><id>Nstein_GL_US_NY_Municipality_942968</id>` +
+		`<variations><variation><name>EU</name></variation></variations></term>` +
+		`<term><name>Financial Times</name><id>ft</id></term></taxonomy>`
+	var out bytes.Buffer
+
+	err := transformTaxonomy(strings.NewReader(in), &out, "ON")
+	assert.NoError(err)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(lines, 2, "Expected one JSON org per line")
+	var first org
+	assert.NoError(json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(transformOrg(term{CanonicalName: "European Union", RawID: "Nstein_GL_US_NY_Municipality_942968", Aliases: aliases{Alias: []alias{alias{Name: "EU"}}}}, "ON"), first)
+}
+
+func TestTransformTaxonomyName(t *testing.T) {
+	assert := assert.New(t)
+	in := `<taxonomy><term><name>Financial Times</name><id>ft</id></term></taxonomy>`
+	var on, gl bytes.Buffer
+
+	assert.NoError(transformTaxonomy(strings.NewReader(in), &on, "ON"))
+	assert.NoError(transformTaxonomy(strings.NewReader(in), &gl, "GL"))
+	assert.NotEqual(on.String(), gl.String(), "Taxonomy name should change the identifiers")
+}
+
+func TestTransformInvalidTaxonomy(t *testing.T) {
+	var out bytes.Buffer
+	assert.Error(t, transformTaxonomy(strings.NewReader("<taxonomy>"), &out, "ON"))
+}
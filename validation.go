@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const maxValidationSamples = 10
+
+// validationRule is a data-quality check run over every transformed org during a load
+type validationRule interface {
+	offends(o org) bool
+}
+
+var validationRules = map[string]func() validationRule{
+	"empty-name":      func() validationRule { return emptyNameRule{} },
+	"blank-alias":     func() validationRule { return blankAliasRule{} },
+	"duplicate-label": func() validationRule { return &duplicateLabelRule{seen: make(map[string]string)} },
+}
+
+func validationRuleNames() []string {
+	var names []string
+	for name := range validationRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// emptyNameRule flags orgs without a usable prefLabel or properName
+type emptyNameRule struct{}
+
+func (emptyNameRule) offends(o org) bool {
+	return strings.TrimSpace(o.PrefLabel) == "" || strings.TrimSpace(o.ProperName) == ""
+}
+
+// blankAliasRule flags orgs with empty or whitespace-only aliases
+type blankAliasRule struct{}
+
+func (blankAliasRule) offends(o org) bool {
+	for _, a := range o.Aliases {
+		if strings.TrimSpace(a) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// duplicateLabelRule flags orgs whose prefLabel is already used by another org in the same load
+type duplicateLabelRule struct {
+	seen map[string]string
+}
+
+func (r *duplicateLabelRule) offends(o org) bool {
+	label := strings.ToLower(strings.TrimSpace(o.PrefLabel))
+	if label == "" {
+		return false
+	}
+	if uuid, ok := r.seen[label]; ok {
+		return uuid != o.UUID
+	}
+	r.seen[label] = o.UUID
+	return false
+}
+
+type ruleResult struct {
+	Rule        string   `json:"rule"`
+	Count       int      `json:"count"`
+	SampleUUIDs []string `json:"sampleUUIDs"`
+}
+
+type validationSummary struct {
+	Checked int          `json:"checked"`
+	Rules   []ruleResult `json:"rules"`
+}
+
+// validationReport collects rule violations for a load. Rules are rebuilt on reset as some keep per-load state.
+type validationReport struct {
+	sync.RWMutex
+	ruleNames []string
+	rules     []validationRule
+	results   []ruleResult
+	checked   int
+}
+
+func newValidationReport(ruleNames []string) (*validationReport, error) {
+	for _, name := range ruleNames {
+		if _, ok := validationRules[name]; !ok {
+			return nil, fmt.Errorf("Unknown validation rule [%v], expected one of %v", name, validationRuleNames())
+		}
+	}
+	r := &validationReport{ruleNames: ruleNames}
+	r.reset()
+	return r, nil
+}
+
+func (r *validationReport) reset() {
+	r.Lock()
+	defer r.Unlock()
+	r.rules = make([]validationRule, len(r.ruleNames))
+	r.results = make([]ruleResult, len(r.ruleNames))
+	for i, name := range r.ruleNames {
+		r.rules[i] = validationRules[name]()
+		r.results[i] = ruleResult{Rule: name, SampleUUIDs: []string{}}
+	}
+	r.checked = 0
+}
+
+// fresh gives an empty report checking the same rules, for a new load
+func (r *validationReport) fresh() *validationReport {
+	f := &validationReport{ruleNames: r.ruleNames}
+	f.reset()
+	return f
+}
+
+func (r *validationReport) validate(o org) {
+	r.Lock()
+	defer r.Unlock()
+	r.checked++
+	for i, rule := range r.rules {
+		if !rule.offends(o) {
+			continue
+		}
+		r.results[i].Count++
+		if len(r.results[i].SampleUUIDs) < maxValidationSamples {
+			r.results[i].SampleUUIDs = append(r.results[i].SampleUUIDs, o.UUID)
+		}
+	}
+}
+
+func (r *validationReport) summary() validationSummary {
+	r.RLock()
+	defer r.RUnlock()
+	s := validationSummary{Checked: r.checked, Rules: make([]ruleResult, len(r.results))}
+	for i, res := range r.results {
+		s.Rules[i] = ruleResult{Rule: res.Rule, Count: res.Count, SampleUUIDs: append([]string{}, res.SampleUUIDs...)}
+	}
+	return s
+}
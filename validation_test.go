@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func defaultValidation() *validationReport {
+	r, err := newValidationReport(validationRuleNames())
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func TestValidationRules(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name   string
+		rule   string
+		orgs   []org
+		count  int
+		sample []string
+	}{
+		{"Empty name", "empty-name", []org{org{UUID: "1", PrefLabel: " ", ProperName: " "}, org{UUID: "2", PrefLabel: "EU", ProperName: "EU"}}, 1, []string{"1"}},
+		{"Blank alias", "blank-alias", []org{org{UUID: "1", Aliases: []string{"EU", "  "}}, org{UUID: "2", Aliases: []string{"EU"}}}, 1, []string{"1"}},
+		{"Duplicate label", "duplicate-label", []org{org{UUID: "1", PrefLabel: "EU"}, org{UUID: "2", PrefLabel: "eu "}, org{UUID: "3", PrefLabel: "EEC"}}, 1, []string{"2"}},
+		{"Same org seen twice is not a duplicate label", "duplicate-label", []org{org{UUID: "1", PrefLabel: "EU"}, org{UUID: "1", PrefLabel: "EU"}}, 0, []string{}},
+	}
+
+	for _, test := range tests {
+		report, err := newValidationReport([]string{test.rule})
+		assert.NoError(err)
+		for _, o := range test.orgs {
+			report.validate(o)
+		}
+		summary := report.summary()
+		assert.Equal(len(test.orgs), summary.Checked, fmt.Sprintf("%s: Checked count incorrect", test.name))
+		assert.Equal(test.count, summary.Rules[0].Count, fmt.Sprintf("%s: Offending count incorrect", test.name))
+		assert.Equal(test.sample, summary.Rules[0].SampleUUIDs, fmt.Sprintf("%s: Samples incorrect", test.name))
+	}
+}
+
+func TestValidationReportReset(t *testing.T) {
+	assert := assert.New(t)
+	report := defaultValidation()
+	report.validate(org{UUID: "1", PrefLabel: "EU", ProperName: "EU"})
+	report.reset()
+	report.validate(org{UUID: "2", PrefLabel: "EU", ProperName: "EU"})
+
+	for _, r := range report.summary().Rules {
+		assert.Equal(0, r.Count, fmt.Sprintf("%s: Rule state should not survive a reset", r.Rule))
+	}
+}
+
+func TestValidationReportFresh(t *testing.T) {
+	assert := assert.New(t)
+	report, _ := newValidationReport([]string{"empty-name"})
+	report.validate(org{UUID: "1"})
+	fresh := report.fresh()
+	fresh.validate(org{UUID: "2", PrefLabel: "EU", ProperName: "EU"})
+
+	assert.Equal(1, report.summary().Rules[0].Count, "A fresh report should leave the original one alone")
+	assert.Equal(validationSummary{Checked: 1, Rules: []ruleResult{ruleResult{Rule: "empty-name", Count: 0, SampleUUIDs: []string{}}}}, fresh.summary())
+}
+
+func TestValidationSamplesAreBounded(t *testing.T) {
+	assert := assert.New(t)
+	report, _ := newValidationReport([]string{"empty-name"})
+	for i := 0; i < maxValidationSamples+5; i++ {
+		report.validate(org{UUID: fmt.Sprint(i)})
+	}
+	assert.Equal(maxValidationSamples+5, report.summary().Rules[0].Count)
+	assert.Len(report.summary().Rules[0].SampleUUIDs, maxValidationSamples)
+}
+
+func TestUnknownValidationRule(t *testing.T) {
+	_, err := newValidationReport([]string{"no-such-rule"})
+	assert.Error(t, err)
+}